@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lunuan/gopkg/log"
+)
+
+type logLevelPayload struct {
+	Level string `json:"level" form:"level"`
+}
+
+// LogLevel returns a gin.HandlerFunc that reads and changes the level of the
+// global logger, e.g.
+//
+//	r.Any("/log/level", middleware.LogLevel())
+//
+// GET returns {"level":"info"}. PUT and POST take the new level either as a
+// JSON body {"level":"debug"} or as a "level" query/form parameter.
+func LogLevel() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet:
+			c.JSON(http.StatusOK, logLevelPayload{Level: log.GetLevel()})
+		case http.MethodPut, http.MethodPost:
+			var req logLevelPayload
+			if err := c.ShouldBind(&req); err != nil || req.Level == "" {
+				req.Level = c.Query("level")
+			}
+			if err := log.SetLevel(req.Level); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, logLevelPayload{Level: log.GetLevel()})
+		default:
+			c.AbortWithStatus(http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lunuan/gopkg/log"
+)
+
+func TestLogLevel(t *testing.T) {
+	defer log.SetLevel(log.GetLevel())
+	if err := log.SetLevel("info"); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Any("/log/level", LogLevel())
+
+	for _, tt := range []struct {
+		method, target, body string
+		status               int
+		want                 string
+	}{
+		{http.MethodGet, "/log/level", "", http.StatusOK, `{"level":"info"}`},
+		{http.MethodPut, "/log/level", `{"level":"debug"}`, http.StatusOK, `{"level":"debug"}`},
+		{http.MethodPut, "/log/level?level=warn", "", http.StatusOK, `{"level":"warn"}`},
+		{http.MethodPost, "/log/level", `{"level":"ERROR"}`, http.StatusOK, `{"level":"error"}`},
+		{http.MethodPut, "/log/level", `{"level":"loud"}`, http.StatusBadRequest, `{"error":`},
+		{http.MethodPut, "/log/level", "", http.StatusBadRequest, `{"error":`},
+		{http.MethodPost, "/log/level", `{"lvl":"debug"}`, http.StatusBadRequest, `{"error":`},
+		{http.MethodGet, "/log/level", "", http.StatusOK, `{"level":"error"}`},
+		{http.MethodDelete, "/log/level", "", http.StatusMethodNotAllowed, ""},
+	} {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		if tt.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != tt.status || !strings.HasPrefix(rec.Body.String(), tt.want) {
+			t.Errorf("%s %s %s: got %d %s, want %d %s", tt.method, tt.target, tt.body, rec.Code, rec.Body, tt.status, tt.want)
+		}
+	}
+}
@@ -2,12 +2,16 @@ package middleware
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"os"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	ginzap "github.com/gin-contrib/zap"
@@ -15,9 +19,12 @@ import (
 	"github.com/lunuan/gopkg/conv"
 	"github.com/lunuan/gopkg/log"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-var logger *zap.Logger
+// logger is the logger of the middleware, read on every request so that
+// SetLogger applies to the handlers built before.
+var logger atomic.Pointer[zap.Logger]
 
 // init init logger use default config
 func init() {
@@ -29,15 +36,80 @@ func init() {
 }
 
 func InitLoggerMiddleware(cfg *log.Config) {
-	logger = log.NewLogger(cfg)
+	logger.Store(log.NewLogger(cfg))
 }
 
+// SetLogger makes the middleware log with l, e.g. log.L().Named("http"),
+// instead of a logger of its own. It applies to the handlers returned by
+// Logger and Recovery before as well.
+func SetLogger(l *log.Logger) {
+	logger.Store(l.Desugar())
+}
+
+// RequestIDHeader is the header a request ID is read from, and echoed back in.
+const RequestIDHeader = "X-Request-ID"
+
+// Logger returns a gin.HandlerFunc that logs requests. Before handling the
+// request it stores the request ID and route in the request context with
+// log.ContextWithFields, so log.InfoCtx(c.Request.Context(), ...) and friends
+// include them, and the access log line carries them too.
 func Logger() gin.HandlerFunc {
-	return ginzap.Ginzap(logger, time.RFC3339, true)
+	conf := &ginzap.Config{
+		TimeFormat:   time.RFC3339,
+		UTC:          true,
+		DefaultLevel: zapcore.InfoLevel,
+		Context: func(c *gin.Context) []zapcore.Field {
+			return log.FieldsFromContext(c.Request.Context())
+		},
+	}
+	handler := currentLogger(func(l *zap.Logger) gin.HandlerFunc {
+		return ginzap.GinzapWithConfig(l, conf)
+	})
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+		ctx := log.ContextWithFields(c.Request.Context(), "request_id", requestID, "route", c.FullPath())
+		c.Request = c.Request.WithContext(ctx)
+		handler(c)
+	}
+}
+
+// loggerHandler is a handler built for a logger, see currentLogger.
+type loggerHandler struct {
+	logger  *zap.Logger
+	handler gin.HandlerFunc
+}
+
+// currentLogger returns a handler running the one build returns for the
+// logger of the middleware, built again only after SetLogger changed it.
+func currentLogger(build func(*zap.Logger) gin.HandlerFunc) gin.HandlerFunc {
+	var cache atomic.Pointer[loggerHandler]
+	return func(c *gin.Context) {
+		l := logger.Load()
+		h := cache.Load()
+		if h == nil || h.logger != l {
+			h = &loggerHandler{logger: l, handler: build(l)}
+			cache.Store(h)
+		}
+		h.handler(c)
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
 }
 
 func Recovery() gin.HandlerFunc {
-	return customRecoveryWithZap(logger, true, defaultHandleRecovery)
+	return currentLogger(func(l *zap.Logger) gin.HandlerFunc {
+		return customRecoveryWithZap(l, true, defaultHandleRecovery)
+	})
 }
 
 func defaultHandleRecovery(c *gin.Context, err interface{}) {
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lunuan/gopkg/log"
+	"github.com/lunuan/gopkg/log/logtest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLoggerRequestID(t *testing.T) {
+	defer logger.Store(logger.Load())
+	l := logtest.New(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Logger())
+	// applies to the middleware built before
+	SetLogger(l.Logger)
+	r.GET("/users/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	req.Header.Set(RequestIDHeader, "abc")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if got := rec.Header().Get(RequestIDHeader); got != "abc" {
+		t.Errorf("echoed request ID %q, want abc", got)
+	}
+	l.AssertLogged("info", "", "request_id", "abc", "route", "/users/:id", "path", "/users/7", "status", 204)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/8", nil))
+	id := rec.Header().Get(RequestIDHeader)
+	if len(id) != 32 {
+		t.Errorf("generated request ID %q, want 32 hex digits", id)
+	}
+	l.AssertLogged("info", "", "request_id", id)
+}
+
+func TestCurrentLogger(t *testing.T) {
+	defer logger.Store(logger.Load())
+	builds := 0
+	handler := currentLogger(func(*zap.Logger) gin.HandlerFunc {
+		builds++
+		return func(*gin.Context) {}
+	})
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	for i := 0; i < 3; i++ {
+		handler(c)
+	}
+	if builds != 1 {
+		t.Errorf("built %d handlers for one logger, want 1", builds)
+	}
+	SetLogger(log.NewWithCore(zapcore.NewNopCore()))
+	handler(c)
+	handler(c)
+	if builds != 2 {
+		t.Errorf("built %d handlers after SetLogger, want 2", builds)
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lunuan/gopkg/log"
+)
+
+// RecentLogs returns a gin.HandlerFunc that serves the latest entries kept by
+// ring, e.g.
+//
+//	ring := log.NewRing(5000)
+//	log.Init(&log.Config{Level: "info", Ring: ring})
+//	r.GET("/log/recent", middleware.RecentLogs(ring))
+//
+// Entries are returned oldest first, as a JSON array, or one per line with
+// format=text. The query parameters filter them, see log.RingFilter:
+//
+//	level=warn              warn and above
+//	logger=http             the http logger and the loggers under it
+//	since=10m, until=...    an RFC 3339 time, or a duration before now
+//	q=timeout               a substring of the message or a key=value field
+//	field=user_id=42        a field value, repeatable
+//	limit=100               the latest 100 entries
+func RecentLogs(ring *log.Ring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := ringFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		entries := ring.Entries(filter)
+		switch c.Query("format") {
+		case "", "json":
+			if entries == nil {
+				entries = []log.RingEntry{}
+			}
+			c.JSON(http.StatusOK, entries)
+		case "text":
+			var b strings.Builder
+			for _, e := range entries {
+				b.WriteString(e.String())
+				b.WriteByte('\n')
+			}
+			c.String(http.StatusOK, b.String())
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown format %q", c.Query("format"))})
+		}
+	}
+}
+
+func ringFilter(c *gin.Context) (log.RingFilter, error) {
+	filter := log.RingFilter{
+		Level:    c.Query("level"),
+		Logger:   c.Query("logger"),
+		Contains: c.Query("q"),
+	}
+	if err := filter.Validate(); err != nil {
+		return filter, err
+	}
+	var err error
+	if filter.Since, err = queryTime(c, "since"); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = queryTime(c, "until"); err != nil {
+		return filter, err
+	}
+	for _, f := range c.QueryArray("field") {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok {
+			return filter, fmt.Errorf("invalid field %q, want key=value", f)
+		}
+		if filter.Fields == nil {
+			filter.Fields = make(map[string]string)
+		}
+		filter.Fields[k] = v
+	}
+	if s := c.Query("limit"); s != "" {
+		if filter.Limit, err = strconv.Atoi(s); err != nil || filter.Limit < 0 {
+			return filter, fmt.Errorf("invalid limit %q", s)
+		}
+	}
+	return filter, nil
+}
+
+// queryTime parses the query parameter key as an RFC 3339 time, or as a
+// duration before now.
+func queryTime(c *gin.Context, key string) (time.Time, error) {
+	s := c.Query(key)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q, want an RFC 3339 time or a duration", key, s)
+	}
+	return t, nil
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lunuan/gopkg/log"
+)
+
+func TestRecentLogs(t *testing.T) {
+	ring := log.NewRing(10)
+	l, err := log.New(&log.Config{Level: "debug", Ring: ring,
+		Outputs: []log.OutputConfig{{Type: log.SinkFile, Path: filepath.Join(t.TempDir(), "app.log")}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.Debug("starting")
+	l.Named("http").Infow("request", "status", 200, "path", "/ping")
+	l.Named("http.client").Warnw("slow request", "status", 200)
+	l.Named("db").Errorw("query failed", "user_id", 42)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/log/recent", RecentLogs(ring))
+
+	hourAgo := url.QueryEscape(time.Now().Add(-time.Hour).Format(time.RFC3339))
+	inAnHour := url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))
+	for _, tt := range []struct {
+		query  string
+		status int
+		want   string
+	}{
+		{"", http.StatusOK, "[starting request slow request query failed]"},
+		{"?level=warn", http.StatusOK, "[slow request query failed]"},
+		{"?logger=http", http.StatusOK, "[request slow request]"},
+		{"?since=1h", http.StatusOK, "[starting request slow request query failed]"},
+		{"?since=" + inAnHour, http.StatusOK, "[]"},
+		{"?until=" + hourAgo, http.StatusOK, "[]"},
+		{"?until=" + inAnHour, http.StatusOK, "[starting request slow request query failed]"},
+		{"?field=status=200", http.StatusOK, "[request slow request]"},
+		{"?field=status=200&field=path=/ping", http.StatusOK, "[request]"},
+		{"?q=user_id=42", http.StatusOK, "[query failed]"},
+		{"?limit=2", http.StatusOK, "[slow request query failed]"},
+		{"?level=warn&logger=db", http.StatusOK, "[query failed]"},
+		{"?level=loud", http.StatusBadRequest, `{"error":`},
+		{"?field=status", http.StatusBadRequest, `{"error":`},
+		{"?limit=-1", http.StatusBadRequest, `{"error":`},
+		{"?limit=ten", http.StatusBadRequest, `{"error":`},
+		{"?format=xml", http.StatusBadRequest, `{"error":`},
+		{"?since=yesterday", http.StatusBadRequest, `{"error":`},
+		{"?until=2026-10-18", http.StatusBadRequest, `{"error":`},
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/log/recent"+tt.query, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s: got %d %s, want %d", tt.query, rec.Code, rec.Body, tt.status)
+			continue
+		}
+		got := rec.Body.String()
+		if tt.status == http.StatusOK {
+			var entries []log.RingEntry
+			if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+				t.Errorf("%s: %v: %s", tt.query, err, rec.Body)
+				continue
+			}
+			msgs := make([]string, len(entries))
+			for i, e := range entries {
+				msgs[i] = e.Message
+			}
+			got = fmt.Sprint(msgs)
+			if got != tt.want {
+				t.Errorf("%s: got %s, want %s", tt.query, got, tt.want)
+			}
+			continue
+		}
+		if !strings.HasPrefix(got, tt.want) {
+			t.Errorf("%s: got %s, want %s", tt.query, got, tt.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/log/recent?format=text&level=warn", nil))
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if rec.Code != http.StatusOK || len(lines) != 2 {
+		t.Fatalf("format=text: got %d %q, want 2 lines", rec.Code, rec.Body)
+	}
+	for i, want := range []string{" WARN http.client ", " ERROR db "} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("format=text line %d: got %q, want %q", i, lines[i], want)
+		}
+	}
+	if !strings.HasSuffix(lines[1], "query failed user_id=42") {
+		t.Errorf("format=text: got %q, want the fields after the message", lines[1])
+	}
+}
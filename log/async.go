@@ -0,0 +1,210 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Overflow policies of AsyncConfig, used when the queue is full.
+const (
+	// OverflowBlock makes the logging goroutine wait for room in the queue.
+	OverflowBlock = "block"
+	// OverflowDropNewest drops the entry being logged.
+	OverflowDropNewest = "drop-newest"
+	// OverflowDropDebugFirst makes room by dropping the oldest queued debug
+	// entry, or drops the entry being logged if it is a debug one. Other
+	// entries wait for room as with OverflowBlock.
+	OverflowDropDebugFirst = "drop-debug-first"
+
+	AsyncQueueSize = 4096
+)
+
+// AsyncConfig configures asynchronous writing. Encoded entries are queued
+// and written to the outputs by a background goroutine, so logging does not
+// wait on slow outputs. Sync flushes the queue.
+type AsyncConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled" mapstructure:"enabled"`
+	// Size is the maximum number of queued entries per output, default
+	// AsyncQueueSize.
+	Size int `yaml:"size" json:"size" mapstructure:"size"`
+	// Overflow is the policy when the queue is full, default OverflowBlock.
+	Overflow string `yaml:"overflow" json:"overflow" mapstructure:"overflow"`
+}
+
+// droppedEntries counts the entries dropped by all async writers.
+var droppedEntries atomic.Uint64
+
+// DroppedEntries returns how many entries async writers dropped because
+// their queue was full.
+func DroppedEntries() uint64 {
+	return droppedEntries.Load()
+}
+
+type asyncEntry struct {
+	level zapcore.Level
+	data  []byte
+}
+
+// asyncWriter queues writes in a bounded ring and writes them to out on a
+// background goroutine.
+type asyncWriter struct {
+	out      zapcore.WriteSyncer
+	overflow string
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond // entries were queued, or the writer was closed
+	notFull  *sync.Cond // entries were taken off the queue
+	idle     *sync.Cond // the background goroutine finished writing a batch
+	ring     []asyncEntry
+	head     int
+	count    int
+	writing  bool
+	closed   bool
+	done     chan struct{}
+}
+
+func newAsyncWriter(out zapcore.WriteSyncer, conf AsyncConfig) *asyncWriter {
+	size := conf.Size
+	if size <= 0 {
+		size = AsyncQueueSize
+	}
+	overflow := conf.Overflow
+	if overflow == "" {
+		overflow = OverflowBlock
+	}
+	w := &asyncWriter{
+		out:      out,
+		overflow: overflow,
+		ring:     make([]asyncEntry, size),
+		done:     make(chan struct{}),
+	}
+	w.notEmpty = sync.NewCond(&w.mu)
+	w.notFull = sync.NewCond(&w.mu)
+	w.idle = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zapcore.InfoLevel, p)
+}
+
+func (w *asyncWriter) WriteLevel(lvl zapcore.Level, p []byte) (int, error) {
+	// the caller frees p once Write returns
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	w.mu.Lock()
+	for !w.closed && w.count == len(w.ring) {
+		if w.overflow == OverflowDropNewest ||
+			(w.overflow == OverflowDropDebugFirst && lvl == zapcore.DebugLevel) {
+			w.mu.Unlock()
+			droppedEntries.Add(1)
+			return len(p), nil
+		}
+		if w.overflow == OverflowDropDebugFirst && w.dropDebug() {
+			droppedEntries.Add(1)
+			break
+		}
+		w.notFull.Wait()
+	}
+	if w.closed {
+		w.mu.Unlock()
+		return writeLevel(w.out, lvl, p)
+	}
+	w.ring[(w.head+w.count)%len(w.ring)] = asyncEntry{level: lvl, data: data}
+	w.count++
+	w.notEmpty.Signal()
+	w.mu.Unlock()
+	return len(p), nil
+}
+
+// dropDebug removes the oldest queued debug entry. It reports false if there
+// is none.
+func (w *asyncWriter) dropDebug() bool {
+	for i := 0; i < w.count; i++ {
+		if w.ring[(w.head+i)%len(w.ring)].level != zapcore.DebugLevel {
+			continue
+		}
+		for j := i; j < w.count-1; j++ {
+			w.ring[(w.head+j)%len(w.ring)] = w.ring[(w.head+j+1)%len(w.ring)]
+		}
+		w.count--
+		w.ring[(w.head+w.count)%len(w.ring)] = asyncEntry{}
+		return true
+	}
+	return false
+}
+
+func (w *asyncWriter) run() {
+	defer close(w.done)
+	batch := make([]asyncEntry, 0, len(w.ring))
+	for {
+		w.mu.Lock()
+		for w.count == 0 && !w.closed {
+			w.notEmpty.Wait()
+		}
+		if w.count == 0 && w.closed {
+			w.mu.Unlock()
+			return
+		}
+		batch = batch[:0]
+		for ; w.count > 0; w.count-- {
+			batch = append(batch, w.ring[w.head])
+			w.ring[w.head] = asyncEntry{}
+			w.head = (w.head + 1) % len(w.ring)
+		}
+		w.writing = true
+		w.notFull.Broadcast()
+		w.mu.Unlock()
+
+		for _, e := range batch {
+			if _, err := writeLevel(w.out, e.level, e.data); err != nil {
+				fmt.Fprintf(os.Stderr, "%v async log write error: %v\n", time.Now(), err)
+			}
+		}
+
+		w.mu.Lock()
+		w.writing = false
+		w.idle.Broadcast()
+		w.mu.Unlock()
+	}
+}
+
+// Sync waits until every queued entry is written, then syncs the output.
+func (w *asyncWriter) Sync() error {
+	w.mu.Lock()
+	for w.count > 0 || w.writing {
+		w.idle.Wait()
+	}
+	w.mu.Unlock()
+	return w.out.Sync()
+}
+
+// Close flushes the queue and stops the background goroutine. Later writes
+// go straight to the output.
+func (w *asyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.notEmpty.Signal()
+	w.notFull.Broadcast()
+	w.mu.Unlock()
+	<-w.done
+	return w.out.Sync()
+}
+
+func writeLevel(out zapcore.WriteSyncer, lvl zapcore.Level, p []byte) (int, error) {
+	if lw, ok := out.(levelWriter); ok {
+		return lw.WriteLevel(lvl, p)
+	}
+	return out.Write(p)
+}
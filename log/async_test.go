@@ -0,0 +1,94 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// gateWriter blocks every write until the gate is opened.
+type gateWriter struct {
+	gate chan struct{}
+	mu   sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) Sync() error { return nil }
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriterFlush(t *testing.T) {
+	out := &gateWriter{gate: make(chan struct{})}
+	close(out.gate)
+	w := newAsyncWriter(out, AsyncConfig{Enabled: true, Size: 4})
+	defer w.Close()
+
+	for i := 0; i < 100; i++ {
+		w.Write([]byte("line\n"))
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(out.String(), "line\n"); got != 100 {
+		t.Errorf("got %d lines after Sync, want 100", got)
+	}
+}
+
+func TestAsyncWriterOverflow(t *testing.T) {
+	tests := []struct {
+		overflow string
+		want     string
+		dropped  uint64
+	}{
+		{OverflowDropNewest, "first|d1|i1|d2|", 2},
+		{OverflowDropDebugFirst, "first|i1|d2|i2|", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.overflow, func(t *testing.T) {
+			out := &gateWriter{gate: make(chan struct{})}
+			w := newAsyncWriter(out, AsyncConfig{Enabled: true, Size: 3, Overflow: tt.overflow})
+
+			// the background goroutine takes "first" off the queue and
+			// blocks writing it, leaving the queue empty
+			w.WriteLevel(zapcore.InfoLevel, []byte("first|"))
+			for {
+				w.mu.Lock()
+				writing := w.writing
+				w.mu.Unlock()
+				if writing {
+					break
+				}
+			}
+
+			before := DroppedEntries()
+			w.WriteLevel(zapcore.DebugLevel, []byte("d1|"))
+			w.WriteLevel(zapcore.InfoLevel, []byte("i1|"))
+			w.WriteLevel(zapcore.DebugLevel, []byte("d2|"))
+			w.WriteLevel(zapcore.InfoLevel, []byte("i2|"))
+			w.WriteLevel(zapcore.DebugLevel, []byte("d3|"))
+
+			close(out.gate)
+			w.Close()
+			if got := out.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if got := DroppedEntries() - before; got != tt.dropped {
+				t.Errorf("got %d dropped entries, want %d", got, tt.dropped)
+			}
+		})
+	}
+}
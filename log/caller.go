@@ -0,0 +1,129 @@
+package log
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// CallerShort writes the caller as package/file.go:line.
+	CallerShort = "short"
+	// CallerFull writes the full path of the caller file.
+	CallerFull = "full"
+)
+
+// CallerConfig configures the caller and the stacktrace of entries.
+type CallerConfig struct {
+	// Disabled leaves the caller out of entries. The loggers then skip
+	// looking it up, unless StacktraceLevel needs it, see L for the global
+	// logger.
+	Disabled bool `yaml:"disabled" json:"disabled" mapstructure:"disabled"`
+	// Path is CallerShort (default) or CallerFull.
+	Path string `yaml:"path" json:"path" mapstructure:"path"`
+	// StacktraceLevel is the lowest level of the entries carrying a
+	// stacktrace of the caller, e.g. "error". Empty means no stacktraces.
+	StacktraceLevel string `yaml:"stacktrace_level" json:"stacktrace_level" mapstructure:"stacktrace_level"`
+	// StacktraceDepth is the most frames a stacktrace has, 0 means no limit.
+	StacktraceDepth int `yaml:"stacktrace_depth" json:"stacktrace_depth" mapstructure:"stacktrace_depth"`
+}
+
+// lookup reports whether the logger has to look up the caller of entries.
+func (c CallerConfig) lookup() bool {
+	return !c.Disabled || c.StacktraceLevel != ""
+}
+
+// callerCore applies a CallerConfig. zap sets the caller once the entry is
+// checked, so callerCore changes the entry when it is written, and checks it
+// against the cores it wraps then.
+type callerCore struct {
+	zapcore.Core
+	noCaller   bool
+	stackLevel zapcore.LevelEnabler
+	depth      int
+}
+
+// newCallerCore wraps core. If conf keeps the caller and asks for no
+// stacktraces core is returned as is.
+func newCallerCore(core zapcore.Core, conf CallerConfig) zapcore.Core {
+	if !conf.Disabled && conf.StacktraceLevel == "" {
+		return core
+	}
+	c := &callerCore{Core: core, noCaller: conf.Disabled, depth: conf.StacktraceDepth}
+	if conf.StacktraceLevel != "" {
+		c.stackLevel = toZapLevel(conf.StacktraceLevel)
+	}
+	return c
+}
+
+func (c *callerCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	return &clone
+}
+
+func (c *callerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *callerCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	caller := ent.Caller
+	if c.noCaller {
+		ent.Caller = zapcore.EntryCaller{}
+	}
+	ce := c.Core.Check(ent, nil)
+	if ce == nil {
+		return nil
+	}
+	if ce.Stack == "" && c.stackLevel != nil && c.stackLevel.Enabled(ent.Level) && caller.Defined {
+		ce.Stack = stacktrace(caller.PC, c.depth)
+	}
+	ce.Write(fields...)
+	return nil
+}
+
+// stacktrace returns the stack of the current goroutine from the frame of pc
+// down, at most depth frames if depth is positive, formatted like zap's.
+func stacktrace(pc uintptr, depth int) string {
+	pcs := make([]uintptr, 64)
+	for {
+		n := runtime.Callers(2, pcs)
+		if n < len(pcs) {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, len(pcs)*2)
+	}
+
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	found, written := false, 0
+	for more := true; more; {
+		var frame runtime.Frame
+		frame, more = frames.Next()
+		if !found {
+			if frame.PC != pc {
+				continue
+			}
+			found = true
+		}
+		if depth > 0 && written == depth {
+			break
+		}
+		if written > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(frame.Function)
+		b.WriteString("\n\t")
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		written++
+	}
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package log
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newJSONTestLogger returns a logger writing JSON entries with conf, and a
+// function decoding the entries written so far.
+func newJSONTestLogger(t *testing.T, conf Config) (*Logger, func() []map[string]interface{}) {
+	t.Helper()
+	conf.Format = FormatJSON
+	l, buf := newBufferLogger(t, &conf)
+	return l, func() []map[string]interface{} {
+		var entries []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var e map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &e); err != nil {
+				t.Fatalf("%v: %s", err, line)
+			}
+			entries = append(entries, e)
+		}
+		return entries
+	}
+}
+
+func TestCallerConfig(t *testing.T) {
+	l, entries := newJSONTestLogger(t, Config{Level: "info",
+		Caller: CallerConfig{Path: CallerFull, StacktraceLevel: "error", StacktraceDepth: 2}})
+	_, file, _, _ := runtime.Caller(0)
+
+	l.Info("no stack")
+	l.Error("with stack")
+	got := entries()
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+	if caller, _ := got[0]["caller"].(string); !strings.HasPrefix(caller, file+":") {
+		t.Errorf("caller %q is not the full path of %s", caller, file)
+	}
+	if _, ok := got[0]["stacktrace"]; ok {
+		t.Errorf("unexpected stacktrace in an info entry: %v", got[0])
+	}
+	stack, _ := got[1]["stacktrace"].(string)
+	frames := strings.Split(stack, "\n\t")
+	if !strings.HasPrefix(stack, "github.com/lunuan/gopkg/log.TestCallerConfig\n") || len(frames) != 3 {
+		t.Errorf("want a 2 frame stacktrace starting at the caller, got:\n%s", stack)
+	}
+
+	l, entries = newJSONTestLogger(t, Config{Level: "info", Caller: CallerConfig{Disabled: true}})
+	l.Info("no caller")
+	if e := entries()[0]; e["caller"] != nil {
+		t.Errorf("unexpected caller in %v", e)
+	}
+	// zap does not look the caller up at all
+	obs, logs := observer.New(zapcore.InfoLevel)
+	l.Desugar().WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core { return obs })).Info("no lookup")
+	if e := logs.All()[0]; e.Caller.Defined {
+		t.Errorf("the caller was looked up: %v", e.Caller)
+	}
+	NewLogger(&Config{Level: "info", Caller: CallerConfig{Disabled: true}}).
+		WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core { return obs })).Info("no lookup")
+	if e := logs.All()[1]; e.Caller.Defined {
+		t.Errorf("NewLogger looked up the caller: %v", e.Caller)
+	}
+
+	conf := Config{Caller: CallerConfig{Path: "relative", StacktraceLevel: "loud", StacktraceDepth: -1}}
+	err := conf.Validate()
+	for _, want := range []string{"caller path", "stacktrace level", "stacktrace depth"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error about the %s, got %v", want, err)
+		}
+	}
+}
+
+// logHelper logs through a helper, as wrapper libraries do.
+func logHelper(l *Logger, msg string) {
+	l.Info(msg)
+}
+
+func TestWithCallerSkip(t *testing.T) {
+	l, entries := newJSONTestLogger(t, Config{Level: "info"})
+	logHelper(l.WithCallerSkip(1), "skipped")
+	_, file, line, _ := runtime.Caller(0)
+
+	caller, _ := entries()[0]["caller"].(string)
+	want := filepath.Base(file) + ":" + strconv.Itoa(line-1)
+	if !strings.HasSuffix(caller, want) {
+		t.Errorf("caller %q, want the line calling the helper %s", caller, want)
+	}
+}
+
+func TestNewLoggerCaller(t *testing.T) {
+	sink, buf := testSink(t)
+	NewSugaredLogger(&Config{Level: "info", Outputs: []OutputConfig{{Type: sink}}}).Info("direct")
+	if !strings.Contains(buf.String(), "caller_test.go") {
+		t.Errorf("wrong caller in %q", buf.String())
+	}
+}
+
+func TestGlobalCallerLookup(t *testing.T) {
+	sink, buf := testSink(t)
+	defer Init(&Config{})
+	obs, logs := observer.New(zapcore.InfoLevel)
+	observe := zap.WrapCore(func(zapcore.Core) zapcore.Core { return obs })
+
+	Init(&Config{Level: "info", Outputs: []OutputConfig{{Type: sink}}, Caller: CallerConfig{Disabled: true}})
+	Info("no caller")
+	if strings.Contains(buf.String(), "caller_test.go") {
+		t.Errorf("unexpected caller in %q", buf.String())
+	}
+	L().Desugar().WithOptions(observe).Info("no lookup")
+	globalLogger().Desugar().WithOptions(observe).Info("no lookup")
+
+	Init(&Config{Level: "info", Outputs: []OutputConfig{{Type: sink}}})
+	L().Desugar().WithOptions(observe).Info("lookup")
+	globalLogger().Desugar().WithOptions(observe).Info("lookup")
+
+	for _, e := range logs.All() {
+		if want := e.Message == "lookup"; e.Caller.Defined != want {
+			t.Errorf("%s: caller looked up %v, want %v", e.Message, e.Caller.Defined, want)
+		}
+	}
+}
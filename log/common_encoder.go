@@ -3,64 +3,205 @@ package log
 import (
 
 	// "encoding/json"
+	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/lunuan/gopkg/log/bufferpool"
 	"go.uber.org/zap/buffer"
 	"go.uber.org/zap/zapcore"
 )
 
+// DefaultCommonLayout is the layout of CommonEncoder unless Config.Layout is
+// set.
+const DefaultCommonLayout = "{time} [{app}] {host} {level} {caller} {fields} {msg}"
+
+// DefaultCommonApp is the {app} token of CommonEncoder unless Config.App is
+// set.
+const DefaultCommonApp = "main"
+
+// Tokens of a CommonEncoder layout.
+const (
+	tokenLiteral = iota
+	tokenTime
+	tokenApp
+	tokenProg
+	tokenHost
+	tokenPid
+	tokenGoroutine
+	tokenLevel
+	tokenLogger
+	tokenCaller
+	tokenFunc
+	tokenFields
+	tokenMsg
+)
+
+var layoutTokens = map[string]int{
+	"time":   tokenTime,
+	"app":    tokenApp,
+	"prog":   tokenProg,
+	"host":   tokenHost,
+	"pid":    tokenPid,
+	"goid":   tokenGoroutine,
+	"level":  tokenLevel,
+	"logger": tokenLogger,
+	"caller": tokenCaller,
+	"func":   tokenFunc,
+	"fields": tokenFields,
+	"msg":    tokenMsg,
+}
+
+type layoutToken struct {
+	kind    int
+	literal string
+}
+
 type CommonEncoder struct {
 	*kvEncoder
+	layout []layoutToken
+	app    string
+	pid    string
 }
 
 func NewCommonEncoder(cfg zapcore.EncoderConfig) *CommonEncoder {
-	return &CommonEncoder{NewkvEncoder(cfg)}
+	enc, _ := NewCommonEncoderWithLayout(cfg, DefaultCommonLayout, "")
+	return enc
 }
 
-func (c CommonEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
-	line := bufferpool.Get()
+// NewCommonEncoderWithLayout returns a CommonEncoder writing entries in the
+// given layout. The layout is a template of {token} placeholders among
+// literal text; the tokens are:
+//
+//	{time}   entry time
+//	{app}    app, or DefaultCommonApp if app is empty
+//	{prog}   program name, from os.Args
+//	{host}   hostname
+//	{pid}    process id
+//	{goid}   id of the logging goroutine
+//	{level}  entry level
+//	{logger} logger name
+//	{caller} file:line of the caller
+//	{func}   function of the caller
+//	{fields} structured context, as key=value pairs
+//	{msg}    message
+//
+// A token with no value, e.g. {caller} when callers are off, is left out
+// together with the blank space before it.
+func NewCommonEncoderWithLayout(cfg zapcore.EncoderConfig, layout string, app string) (*CommonEncoder, error) {
+	if layout == "" {
+		layout = DefaultCommonLayout
+	}
+	tokens, err := parseLayout(layout)
+	if err != nil {
+		return nil, err
+	}
+	return &CommonEncoder{
+		kvEncoder: NewkvEncoder(cfg),
+		layout:    tokens,
+		app:       commonApp(app),
+		pid:       strconv.Itoa(os.Getpid()),
+	}, nil
+}
 
-	// We don't want the entry's metadata to be quoted and escaped (if it's
-	// encoded as strings), which means that we can't use the JSON encoder. The
-	// simplest option is to use the memory encoder and fmt.Fprint.
-	//
-	// If this ever becomes a performance bottleneck, we can implement
-	// ArrayEncoder for our plain-text format.
-	arr := getSliceEncoder()
-	// timestrap
-	if c.TimeKey != "" && c.EncodeTime != nil && !ent.Time.IsZero() {
-		c.EncodeTime(ent.Time, arr)
+// appName returns app, or the program name if app is empty.
+func appName(app string) string {
+	if app == "" {
+		return filepath.Base(os.Args[0])
 	}
-	// [process]
-	arr.AppendString("[main]")
-	// hostname
-	arr.AppendString(c.hostname)
-	// level
-	if c.LevelKey != "" && c.EncodeLevel != nil {
-		c.EncodeLevel(ent.Level, arr)
+	return app
+}
+
+// commonApp returns app, or DefaultCommonApp if app is empty.
+func commonApp(app string) string {
+	if app == "" {
+		return DefaultCommonApp
 	}
+	return app
+}
 
-	// caller
-	if ent.Caller.Defined && c.EncodeCaller != nil {
-		c.EncodeCaller(ent.Caller, arr)
+func parseLayout(layout string) ([]layoutToken, error) {
+	var tokens []layoutToken
+	for layout != "" {
+		start := strings.IndexByte(layout, '{')
+		if start < 0 {
+			tokens = append(tokens, layoutToken{kind: tokenLiteral, literal: layout})
+			break
+		}
+		end := strings.IndexByte(layout[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("log: layout %q: unclosed {", layout)
+		}
+		end += start
+		if start > 0 {
+			tokens = append(tokens, layoutToken{kind: tokenLiteral, literal: layout[:start]})
+		}
+		name := layout[start+1 : end]
+		kind, ok := layoutTokens[name]
+		if !ok {
+			return nil, fmt.Errorf("log: layout: unknown token {%s}", name)
+		}
+		tokens = append(tokens, layoutToken{kind: kind})
+		layout = layout[end+1:]
 	}
+	return tokens, nil
+}
 
-	for i := range arr.elems {
-		if i > 0 {
-			line.AppendString(c.ConsoleSeparator)
+func (c *CommonEncoder) configure(conf *Config) error {
+	if conf.Layout != "" {
+		tokens, err := parseLayout(conf.Layout)
+		if err != nil {
+			return err
 		}
-		fmt.Fprint(line, arr.elems[i])
+		c.layout = tokens
 	}
-	putSliceEncoder(arr)
+	c.app = commonApp(conf.App)
+	return c.kvEncoder.configure(conf)
+}
+
+func (c CommonEncoder) Clone() zapcore.Encoder {
+	return &CommonEncoder{
+		kvEncoder: c.kvEncoder.Clone().(*kvEncoder),
+		layout:    c.layout,
+		app:       c.app,
+		pid:       c.pid,
+	}
+}
 
-	// Add any structured context.
-	c.writeContext(line, fields)
+func (c CommonEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	line := bufferpool.Get()
+	token := bufferpool.Get()
+	defer token.Free()
 
-	// Add the message itself.
-	if c.MessageKey != "" {
-		c.addSeparatorIfNecessary(line)
-		line.AppendString(ent.Message)
+	// blank literals are held back until the next token turns out to have
+	// a value, so that missing tokens don't leave runs of blanks behind
+	var pending string
+	for _, t := range c.layout {
+		if t.kind == tokenLiteral {
+			if strings.TrimSpace(t.literal) == "" {
+				pending = t.literal
+				continue
+			}
+			line.AppendString(pending)
+			line.AppendString(t.literal)
+			pending = ""
+			continue
+		}
+
+		token.Reset()
+		c.appendToken(token, t.kind, ent, fields)
+		if token.Len() == 0 {
+			continue
+		}
+		if line.Len() > 0 {
+			line.AppendString(pending)
+		}
+		pending = ""
+		line.Write(token.Bytes())
 	}
 
 	// If there's no stacktrace key, honor that; this allows users to force
@@ -73,3 +214,77 @@ func (c CommonEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*
 	line.AppendString(c.LineEnding)
 	return line, nil
 }
+
+func (c CommonEncoder) appendToken(buf *buffer.Buffer, kind int, ent zapcore.Entry, fields []zapcore.Field) {
+	switch kind {
+	case tokenTime:
+		if c.TimeKey != "" && c.EncodeTime != nil && !ent.Time.IsZero() {
+			appendPrimitive(buf, func(arr zapcore.PrimitiveArrayEncoder) { c.EncodeTime(ent.Time, arr) })
+		}
+	case tokenApp:
+		buf.AppendString(c.app)
+	case tokenProg:
+		buf.AppendString(appName(""))
+	case tokenHost:
+		buf.AppendString(c.hostname)
+	case tokenPid:
+		buf.AppendString(c.pid)
+	case tokenGoroutine:
+		buf.AppendUint(goroutineID())
+	case tokenLevel:
+		if c.LevelKey != "" && c.EncodeLevel != nil {
+			appendPrimitive(buf, func(arr zapcore.PrimitiveArrayEncoder) { c.EncodeLevel(ent.Level, arr) })
+		}
+	case tokenLogger:
+		if ent.LoggerName != "" {
+			nameEncoder := c.EncodeName
+			if nameEncoder == nil {
+				nameEncoder = zapcore.FullNameEncoder
+			}
+			appendPrimitive(buf, func(arr zapcore.PrimitiveArrayEncoder) { nameEncoder(ent.LoggerName, arr) })
+		}
+	case tokenCaller:
+		if ent.Caller.Defined && c.EncodeCaller != nil {
+			appendPrimitive(buf, func(arr zapcore.PrimitiveArrayEncoder) { c.EncodeCaller(ent.Caller, arr) })
+		}
+	case tokenFunc:
+		if ent.Caller.Defined {
+			buf.AppendString(ent.Caller.Function)
+		}
+	case tokenFields:
+		c.writeContext(buf, fields)
+	case tokenMsg:
+		if c.MessageKey != "" {
+			buf.AppendString(ent.Message)
+		}
+	}
+}
+
+// appendPrimitive writes the values encoded by encode to buf, separated by
+// spaces.
+func appendPrimitive(buf *buffer.Buffer, encode func(zapcore.PrimitiveArrayEncoder)) {
+	arr := getSliceEncoder()
+	encode(arr)
+	for i := range arr.elems {
+		if i > 0 {
+			buf.AppendByte(' ')
+		}
+		fmt.Fprint(buf, arr.elems[i])
+	}
+	putSliceEncoder(arr)
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+// goroutineID returns the id of the calling goroutine, parsed from the
+// header of its stack trace.
+func goroutineID() uint64 {
+	var stack [64]byte
+	b := stack[:runtime.Stack(stack[:], false)]
+	b = bytes.TrimPrefix(b, goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
@@ -0,0 +1,89 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestCommonEncoderLayout(t *testing.T) {
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.EncodeTime = zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05")
+	cfg.EncodeLevel = zapcore.CapitalLevelEncoder
+	cfg.EncodeCaller = zapcore.ShortCallerEncoder
+	cfg.NameKey = "logger"
+
+	ent := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC),
+		LoggerName: "pushservice",
+		Message:    "push failed",
+		Caller:     zapcore.NewEntryCaller(0, "/src/gopkg/prometheus/push.go", 42, true),
+	}
+	fields := []zapcore.Field{zap.Int("code", 503)}
+
+	tests := []struct {
+		layout string
+		ent    zapcore.Entry
+		fields []zapcore.Field
+		want   string
+	}{
+		{
+			layout: "{time} [{app}] {level} {caller} {fields} {msg}",
+			ent:    ent,
+			fields: fields,
+			want:   "2026-10-18 08:00:00 [myapp] WARN prometheus/push.go:42 code=503 push failed\n",
+		},
+		{
+			layout: "{prog} {msg}",
+			ent:    ent,
+			want:   filepath.Base(os.Args[0]) + " push failed\n",
+		},
+		{
+			layout: "{level}|{logger}|{msg}",
+			ent:    ent,
+			want:   "WARN|pushservice|push failed\n",
+		},
+		{
+			// missing tokens take their leading blanks with them
+			layout: "{time}  {caller} {fields} {msg}",
+			ent:    zapcore.Entry{Time: ent.Time, Message: "hello"},
+			want:   "2026-10-18 08:00:00 hello\n",
+		},
+	}
+	for _, tt := range tests {
+		enc, err := NewCommonEncoderWithLayout(cfg, tt.layout, "myapp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := enc.EncodeEntry(tt.ent, tt.fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("layout %q:\ngot  %q\nwant %q", tt.layout, got, tt.want)
+		}
+		buf.Free()
+	}
+
+	if enc := NewCommonEncoder(cfg); enc.app != DefaultCommonApp {
+		t.Errorf("default app %q, want %q", enc.app, DefaultCommonApp)
+	}
+
+	if _, err := NewCommonEncoderWithLayout(cfg, "{time} {nope}", ""); err == nil {
+		t.Error("expected an error for an unknown token")
+	}
+
+	// a clone keeps the layout, along with the added fields
+	enc, _ := NewCommonEncoderWithLayout(cfg, "{level} {fields} {msg}", "")
+	clone := enc.Clone()
+	clone.AddString("k", "v")
+	buf, _ := clone.EncodeEntry(ent, nil)
+	if got, want := buf.String(), "WARN k=v push failed\n"; got != want {
+		t.Errorf("clone: got %q, want %q", got, want)
+	}
+}
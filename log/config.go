@@ -0,0 +1,331 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfig returns the configuration LoadConfig starts from. The
+// rotation settings are left at 0, for the defaults of the rotation mode.
+func DefaultConfig() *Config {
+	return &Config{
+		Format: FormatKV,
+		Level:  "info",
+	}
+}
+
+// LoadConfig merges, in this order, DefaultConfig, the YAML or JSON file at
+// path and the environment variables starting with envPrefix, see
+// ConfigFromEnv. Each source overrides the settings it has of the ones
+// before it; an empty path or envPrefix skips that source. Unknown keys in
+// the file are errors.
+func LoadConfig(path, envPrefix string) (*Config, error) {
+	conf := DefaultConfig()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("log: load config: %w", err)
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(conf); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("log: load config %s: %w", path, err)
+		}
+	}
+	if envPrefix != "" {
+		if err := applyEnv(reflect.ValueOf(conf).Elem(), envPrefix); err != nil {
+			return nil, err
+		}
+	}
+	return conf, nil
+}
+
+// ConfigFromEnv returns the configuration set by the environment variables
+// starting with prefix. A variable is named after the path of its field,
+// upper-cased and joined by '_', e.g. with prefix APP_LOG:
+//
+//	APP_LOG_LEVEL=debug
+//	APP_LOG_ROTATE_MAXSIZE=500
+//	APP_LOG_LEVELS=http=debug,pushservice=warn
+//	APP_LOG_REDACT_KEYS=password,*token*
+//	APP_LOG_RATELIMIT_WINDOW=1s
+//	APP_LOG_OUTPUTS=[{"type": "stderr"}, {"type": "udp", "address": "127.0.0.1:514"}]
+//
+// Lists and maps of strings are comma separated, other lists and maps, like
+// Outputs, are YAML or JSON.
+func ConfigFromEnv(prefix string) (*Config, error) {
+	conf := &Config{}
+	if err := applyEnv(reflect.ValueOf(conf).Elem(), prefix); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv sets the fields of the struct v from the environment variables
+// named prefix_FIELD. The fields a file can't set, tagged yaml:"-", are
+// skipped.
+func applyEnv(v reflect.Value, prefix string) error {
+	prefix = strings.TrimSuffix(prefix, "_")
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("yaml") == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(field.Name)
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnv(v.Field(i), name); err != nil {
+				return err
+			}
+			continue
+		}
+		s, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setEnvValue(v.Field(i), s); err != nil {
+			return fmt.Errorf("log: env %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setEnvValue(v reflect.Value, s string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String && v.Type().Elem().Kind() == reflect.String:
+		m := make(map[string]string)
+		for _, pair := range strings.Split(s, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("%q is not a key=value pair", pair)
+			}
+			m[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+		v.Set(reflect.ValueOf(m))
+	case v.Kind() == reflect.Pointer || v.Kind() == reflect.Func || v.Kind() == reflect.Chan || v.Kind() == reflect.Interface:
+		return fmt.Errorf("a %s can't be set from the environment", v.Type())
+	default:
+		ptr := reflect.New(v.Type())
+		if err := yaml.Unmarshal([]byte(s), ptr.Interface()); err != nil {
+			return err
+		}
+		v.Set(ptr.Elem())
+	}
+	return nil
+}
+
+// Validate reports every problem of c at once: unknown levels, formats and
+// sink types, file paths that can't be written to and rotation settings out
+// of range. It creates nothing: a file path that doesn't exist yet is
+// checked against its nearest existing parent directory.
+func (c *Config) Validate() error {
+	var errs []error
+	if _, err := parseLevel(c.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log: invalid level %q", c.Level))
+	}
+	for name, lvl := range c.Levels {
+		if _, err := parseLevel(lvl); err != nil {
+			errs = append(errs, fmt.Errorf("log: invalid level %q for logger %q", lvl, name))
+		}
+	}
+	if !encoderRegistered(c.Format) {
+		errs = append(errs, fmt.Errorf("log: unknown format %q", c.Format))
+	}
+	if c.ObjectStyle != "" && c.ObjectStyle != ObjectStyleBraces && c.ObjectStyle != ObjectStyleFlatten {
+		errs = append(errs, fmt.Errorf("log: unknown object style %q", c.ObjectStyle))
+	}
+	if c.Caller.Path != "" && c.Caller.Path != CallerShort && c.Caller.Path != CallerFull {
+		errs = append(errs, fmt.Errorf("log: unknown caller path %q", c.Caller.Path))
+	}
+	if c.Caller.StacktraceLevel != "" {
+		if _, err := parseLevel(c.Caller.StacktraceLevel); err != nil {
+			errs = append(errs, fmt.Errorf("log: invalid stacktrace level %q", c.Caller.StacktraceLevel))
+		}
+	}
+	if c.Caller.StacktraceDepth < 0 {
+		errs = append(errs, fmt.Errorf("log: invalid stacktrace depth %d", c.Caller.StacktraceDepth))
+	}
+	if c.Layout != "" {
+		if _, err := parseLayout(c.Layout); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.Async.Overflow != "" && c.Async.Overflow != OverflowBlock &&
+		c.Async.Overflow != OverflowDropNewest && c.Async.Overflow != OverflowDropDebugFirst {
+		errs = append(errs, fmt.Errorf("log: unknown async overflow policy %q", c.Async.Overflow))
+	}
+	if c.Async.Size < 0 {
+		errs = append(errs, fmt.Errorf("log: async queue size %d is negative", c.Async.Size))
+	}
+
+	switch c.OutputMode {
+	case "", OutputBoth, OutputConsole:
+	case OutputFile:
+		if c.FilePath == "" && len(c.Outputs) == 0 {
+			errs = append(errs, errors.New("log: output mode file needs a file path"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("log: unknown output mode %q", c.OutputMode))
+	}
+	if c.Console != "" && c.Console != SinkStdout && c.Console != SinkStderr {
+		errs = append(errs, fmt.Errorf("log: console %q is neither stdout nor stderr", c.Console))
+	}
+
+	outputs, prefix := c.Outputs, "outputs[%d]: "
+	if len(outputs) == 0 {
+		outputs, prefix = defaultOutputs(c), ""
+	}
+	for i, output := range outputs {
+		for _, err := range output.validate() {
+			if prefix != "" {
+				err = fmt.Errorf(prefix+"%w", i, err)
+			}
+			errs = append(errs, fmt.Errorf("log: %w", err))
+		}
+	}
+	if c.ErrorFilePath != "" {
+		if filepath.Clean(c.ErrorFilePath) == filepath.Clean(c.FilePath) {
+			errs = append(errs, fmt.Errorf("log: error file path %q is the file path", c.ErrorFilePath))
+		}
+		for _, err := range errorOutput(c).validate() {
+			errs = append(errs, fmt.Errorf("log: error file: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (o OutputConfig) validate() []error {
+	var errs []error
+	typ := o.Type
+	if typ == "" {
+		typ = SinkStdout
+	}
+	sinkMu.RLock()
+	_, ok := sinkTypes[typ]
+	sinkMu.RUnlock()
+	if !ok {
+		errs = append(errs, fmt.Errorf("unknown sink type %q", typ))
+	}
+	if o.Format != "" && !encoderRegistered(o.Format) {
+		errs = append(errs, fmt.Errorf("unknown format %q", o.Format))
+	}
+	if o.Level != "" {
+		if _, err := parseLevel(o.Level); err != nil {
+			errs = append(errs, fmt.Errorf("invalid level %q", o.Level))
+		}
+	}
+	if typ == SinkFile {
+		if o.Path == "" {
+			errs = append(errs, errors.New("file path is empty"))
+		} else if o.Rotate.timed() {
+			if err := checkDirWritable(filepath.Dir(o.Path)); err != nil {
+				errs = append(errs, fmt.Errorf("directory of file path %q is not writable: %w", o.Path, err))
+			}
+			if _, err := compileRotatePattern(o.Rotate.pattern(o.Path), o.Rotate.Mode); err != nil {
+				errs = append(errs, err)
+			}
+		} else if err := checkWritable(o.Path); err != nil {
+			errs = append(errs, fmt.Errorf("file path %q is not writable: %w", o.Path, err))
+		}
+		errs = append(errs, o.Rotate.validate()...)
+	}
+	return errs
+}
+
+func (r RotateConfig) validate() []error {
+	var errs []error
+	if r.Mode != "" && r.Mode != RotateSize && !r.timed() {
+		errs = append(errs, fmt.Errorf("unknown rotate mode %q", r.Mode))
+	}
+	if r.MaxSize != 0 && r.MaxSize <= 100 {
+		errs = append(errs, fmt.Errorf("rotate max size %dMB out of range: must be 0 (default %d) or more than 100", r.MaxSize, RotateMaxSize))
+	}
+	if r.MaxAge != 0 && r.MaxAge < 3 {
+		errs = append(errs, fmt.Errorf("rotate max age %d days out of range: must be 0 (default %d) or at least 3", r.MaxAge, RotateMaxAge))
+	}
+	if r.MaxBackups != 0 && r.MaxBackups < 3 {
+		errs = append(errs, fmt.Errorf("rotate max backups %d out of range: must be 0 (default %d) or at least 3", r.MaxBackups, RotateMaxBackups))
+	}
+	return errs
+}
+
+// checkDirWritable checks that dir, or its nearest existing parent if dir
+// doesn't exist yet, is a directory the process can write to.
+func checkDirWritable(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		switch {
+		case err == nil && !info.IsDir():
+			return fmt.Errorf("%s is not a directory", dir)
+		case err == nil:
+			return canWrite(dir)
+		case !errors.Is(err, fs.ErrNotExist):
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+}
+
+// checkWritable checks that the file sink can append to path: path is a
+// file it can write to, or can be created in its directory. Nothing is
+// created, the sink does that.
+func checkWritable(path string) error {
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && info.IsDir():
+		return fmt.Errorf("%s is a directory", path)
+	case err == nil:
+		return canWrite(path)
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+	return checkDirWritable(filepath.Dir(path))
+}
@@ -0,0 +1,202 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewValidatesConfig(t *testing.T) {
+	dir := t.TempDir()
+	readOnly := filepath.Join(dir, "ro")
+	if err := os.Mkdir(readOnly, 0555); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		conf Config
+		want []string
+	}{
+		{name: "valid", conf: Config{Level: "warn", Format: FormatJSON, FilePath: filepath.Join(dir, "logs", "app.log")}},
+		{name: "defaults", conf: Config{}},
+		{name: "daily", conf: Config{FilePath: filepath.Join(dir, "daily", "app.log"), Rotate: RotateConfig{Mode: RotateDaily}}},
+		{name: "level", conf: Config{Level: "verbose"}, want: []string{`invalid level "verbose"`}},
+		{
+			name: "named level",
+			conf: Config{Levels: map[string]string{"http": "loud"}},
+			want: []string{`invalid level "loud" for logger "http"`},
+		},
+		{name: "format", conf: Config{Format: "xml"}, want: []string{`unknown format "xml"`}},
+		{name: "directory", conf: Config{FilePath: dir}, want: []string{"is not writable"}},
+		{
+			name: "rotation",
+			conf: Config{FilePath: filepath.Join(dir, "app.log"), Rotate: RotateConfig{MaxSize: 50, MaxAge: 1, MaxBackups: -1}},
+			want: []string{"max size 50MB out of range", "max age 1 days out of range", "max backups -1 out of range"},
+		},
+		{
+			name: "outputs",
+			conf: Config{Outputs: []OutputConfig{{Type: SinkStdout}, {Type: SinkFile, Level: "nope"}}},
+			want: []string{`outputs[1]: invalid level "nope"`, "outputs[1]: file path is empty"},
+		},
+	}
+	if os.Geteuid() != 0 {
+		tests = append(tests, struct {
+			name string
+			conf Config
+			want []string
+		}{name: "read-only", conf: Config{FilePath: filepath.Join(readOnly, "app.log")}, want: []string{"is not writable"}})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, err := New(&tt.conf)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				log.Info("hello")
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCreatesNothing(t *testing.T) {
+	dir := t.TempDir()
+	conf := &Config{
+		ErrorFilePath: filepath.Join(dir, "logs", "error.log"),
+		Outputs: []OutputConfig{
+			{Type: SinkFile, Path: filepath.Join(dir, "daily", "app.log"), Rotate: RotateConfig{Mode: RotateDaily}},
+		},
+	}
+	if err := conf.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Validate created %v", entries)
+	}
+
+	l, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for _, path := range []string{"logs/error.log", "daily"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("New did not create %s: %v", path, err)
+		}
+	}
+}
+
+func TestInitPanicsOnInvalidConfig(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	Init(&Config{Level: "verbose"})
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.yaml")
+	err := os.WriteFile(path, []byte(`
+level: warn
+format: json
+file_path: /var/log/app.log
+rotate:
+  max_size: 500
+levels:
+  http: debug
+rate_limit:
+  window: 2s
+  burst: 10
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP_LOG_LEVEL", "error")
+	t.Setenv("APP_LOG_ROTATE_MAXBACKUPS", "30")
+	t.Setenv("APP_LOG_REDACT_KEYS", "password, *token*")
+
+	conf, err := LoadConfig(path, "APP_LOG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultConfig()
+	want.Level = "error"
+	want.Format = FormatJSON
+	want.FilePath = "/var/log/app.log"
+	want.Rotate.MaxSize = 500
+	want.Rotate.MaxBackups = 30
+	want.Levels = map[string]string{"http": "debug"}
+	want.RateLimit = RateLimitConfig{Window: 2 * time.Second, Burst: 10}
+	want.Redact.Keys = []string{"password", "*token*"}
+	if !reflect.DeepEqual(conf, want) {
+		t.Errorf("got  %+v\nwant %+v", conf, want)
+	}
+
+	// timed rotation keeps its own defaults, see RotateConfig.MaxBackups
+	if err := os.WriteFile(path, []byte("rotate: {mode: daily, max_age: 30}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if conf, err = LoadConfig(path, ""); err != nil {
+		t.Fatal(err)
+	}
+	if want := (RotateConfig{Mode: RotateDaily, MaxAge: 30}); conf.Rotate != want {
+		t.Errorf("got rotate %+v, want %+v", conf.Rotate, want)
+	}
+
+	if err := os.WriteFile(path, []byte("levle: warn\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path, ""); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("SVC_LEVELS", "http=debug,pushservice=warn")
+	t.Setenv("SVC_ASYNC_ENABLED", "true")
+	t.Setenv("SVC_SAMPLING_TICK", "500ms")
+	t.Setenv("SVC_OUTPUTS", `[{"type": "stderr"}, {"type": "udp", "address": "127.0.0.1:514"}]`)
+
+	conf, err := ConfigFromEnv("SVC_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Config{
+		Levels:   map[string]string{"http": "debug", "pushservice": "warn"},
+		Async:    AsyncConfig{Enabled: true},
+		Sampling: SamplingConfig{Tick: 500 * time.Millisecond},
+		Outputs:  []OutputConfig{{Type: SinkStderr}, {Type: SinkUDP, Address: "127.0.0.1:514"}},
+	}
+	if !reflect.DeepEqual(conf, want) {
+		t.Errorf("got  %+v\nwant %+v", conf, want)
+	}
+
+	// Ring is set in code only
+	t.Setenv("SVC_RING", "{}")
+	if conf, err = ConfigFromEnv("SVC"); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Ring != nil {
+		t.Errorf("SVC_RING set the ring: %+v", conf.Ring)
+	}
+
+	t.Setenv("SVC_ROTATE_MAXSIZE", "big")
+	if _, err := ConfigFromEnv("SVC"); err == nil || !strings.Contains(err.Error(), "SVC_ROTATE_MAXSIZE") {
+		t.Errorf("expected an error naming the variable, got %v", err)
+	}
+}
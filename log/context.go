@@ -0,0 +1,98 @@
+package log
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+type fieldsKey struct{}
+
+// ContextWithFields returns a copy of ctx carrying keysAndValues, in addition
+// to any fields already stored in ctx. keysAndValues take the same form as
+// the ones of Infow; zap.Field values are accepted as well.
+func ContextWithFields(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	fields := sweetenFields(keysAndValues)
+	if len(fields) == 0 {
+		return ctx
+	}
+	parent := FieldsFromContext(ctx)
+	merged := make([]zap.Field, 0, len(parent)+len(fields))
+	merged = append(merged, parent...)
+	merged = append(merged, fields...)
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// FieldsFromContext returns the fields stored in ctx by ContextWithFields.
+func FieldsFromContext(ctx context.Context) []zap.Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).([]zap.Field)
+	return fields
+}
+
+// WithContext returns the global logger, see L, with the fields stored in
+// ctx added.
+func WithContext(ctx context.Context) *Logger {
+	l := L()
+	fields := FieldsFromContext(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return &Logger{sugar: l.sugar.Desugar().With(fields...).Sugar(), closer: l.closer}
+}
+
+func ctxLogger(ctx context.Context) *zap.SugaredLogger {
+	l := globalLogger()
+	fields := FieldsFromContext(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return l.Desugar().With(fields...).Sugar()
+}
+
+// sweetenFields turns loosely typed key-value pairs into zap fields, the same
+// way zap.SugaredLogger does. A dangling key is kept with a nil value and a
+// non-string key is stringified.
+func sweetenFields(args []interface{}) []zap.Field {
+	fields := make([]zap.Field, 0, len(args)/2)
+	for i := 0; i < len(args); {
+		if f, ok := args[i].(zap.Field); ok {
+			fields = append(fields, f)
+			i++
+			continue
+		}
+		if i == len(args)-1 {
+			fields = append(fields, zap.Any(toKey(args[i]), nil))
+			break
+		}
+		fields = append(fields, zap.Any(toKey(args[i]), args[i+1]))
+		i += 2
+	}
+	return fields
+}
+
+func toKey(k interface{}) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	return fmt.Sprint(k)
+}
+
+func DebugCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	ctxLogger(ctx).Debugw(msg, keysAndValues...)
+}
+
+func InfoCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	ctxLogger(ctx).Infow(msg, keysAndValues...)
+}
+
+func WarnCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	ctxLogger(ctx).Warnw(msg, keysAndValues...)
+}
+
+func ErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	ctxLogger(ctx).Errorw(msg, keysAndValues...)
+}
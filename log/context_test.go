@@ -0,0 +1,75 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestContextWithFields(t *testing.T) {
+	ctx := ContextWithFields(context.Background(), "request_id", "abc", zap.Int("tenant", 7))
+	ctx = ContextWithFields(ctx, "route", "/ping", "dangling")
+
+	fields := FieldsFromContext(ctx)
+	want := []string{"request_id", "tenant", "route", "dangling"}
+	if len(fields) != len(want) {
+		t.Fatalf("got %d fields, want %d", len(fields), len(want))
+	}
+	for i, key := range want {
+		if fields[i].Key != key {
+			t.Errorf("field %d: got key %q, want %q", i, fields[i].Key, key)
+		}
+	}
+
+	if got := FieldsFromContext(context.Background()); got != nil {
+		t.Errorf("got %v fields from an empty context", got)
+	}
+}
+
+func TestSweetenFields(t *testing.T) {
+	fields := sweetenFields([]interface{}{"a", 1, zap.String("b", "2"), 3, "c", "dangling"})
+	want := []zap.Field{zap.Any("a", 1), zap.String("b", "2"), zap.Any("3", "c"), zap.Any("dangling", nil)}
+	if len(fields) != len(want) {
+		t.Fatalf("got %d fields, want %d: %v", len(fields), len(want), fields)
+	}
+	for i := range want {
+		if !fields[i].Equals(want[i]) {
+			t.Errorf("field %d: got %+v, want %+v", i, fields[i], want[i])
+		}
+	}
+	if got := sweetenFields(nil); len(got) != 0 {
+		t.Errorf("got %v from no pairs", got)
+	}
+}
+
+func TestCtxHelpers(t *testing.T) {
+	sink, buf := testSink(t)
+	Init(&Config{Level: "debug", Outputs: []OutputConfig{{Type: sink}}})
+	defer Init(&Config{})
+
+	ctx := ContextWithFields(context.Background(), "request_id", "abc")
+	DebugCtx(ctx, "debug", "k", 1)
+	InfoCtx(ctx, "info", "k", 2)
+	WarnCtx(ctx, "warn", "k", 3)
+	ErrorCtx(ctx, "error", "k", 4)
+	WithContext(ctx).Infow("direct", "k", 5)
+	InfoCtx(context.Background(), "bare")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("got %d lines, want 6: %q", len(lines), buf.String())
+	}
+	for i, msg := range []string{"debug", "info", "warn", "error", "direct"} {
+		for _, want := range []string{"request_id=abc", fmt.Sprintf("k=%d", i+1), "message=" + msg, "context_test.go"} {
+			if !strings.Contains(lines[i], want) {
+				t.Errorf("missing %q in %q", want, lines[i])
+			}
+		}
+	}
+	if strings.Contains(lines[5], "request_id") || !strings.Contains(lines[5], "context_test.go") {
+		t.Errorf("unexpected fields or caller in %q", lines[5])
+	}
+}
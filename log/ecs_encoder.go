@@ -0,0 +1,72 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// ecsVersion is the version of the Elastic Common Schema written by
+// ecsEncoder.
+const ecsVersion = "1.6.0"
+
+// ecsEncoder writes entries as Elastic Common Schema JSON objects, with the
+// entry under @timestamp, log.level, log.logger, log.origin.* and message,
+// and the app under service.name.
+type ecsEncoder struct {
+	zapcore.Encoder
+}
+
+func newECSEncoder(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	cfg.TimeKey = "@timestamp"
+	cfg.EncodeTime = ecsTimeEncoder
+	cfg.LevelKey = "log.level"
+	cfg.EncodeLevel = zapcore.LowercaseLevelEncoder
+	cfg.NameKey = "log.logger"
+	cfg.MessageKey = "message"
+	cfg.StacktraceKey = "error.stack_trace"
+	// the caller is written by EncodeEntry, as several fields
+	cfg.CallerKey = ""
+	cfg.FunctionKey = ""
+
+	enc := zapcore.NewJSONEncoder(cfg)
+	enc.AddString("ecs.version", ecsVersion)
+	enc.AddString("host.hostname", hostname())
+	enc.AddInt("process.pid", os.Getpid())
+	return &ecsEncoder{Encoder: enc}, nil
+}
+
+func (enc *ecsEncoder) configure(conf *Config) error {
+	enc.AddString("service.name", appName(conf.App))
+	return nil
+}
+
+func ecsTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(t.UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+}
+
+func (enc *ecsEncoder) Clone() zapcore.Encoder {
+	return &ecsEncoder{Encoder: enc.Encoder.Clone()}
+}
+
+func (enc *ecsEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	if ent.Caller.Defined {
+		file := ent.Caller.TrimmedPath()
+		if i := strings.LastIndexByte(file, ':'); i > 0 {
+			file = file[:i]
+		}
+		origin := []zapcore.Field{
+			zap.String("log.origin.file.name", file),
+			zap.Int("log.origin.file.line", ent.Caller.Line),
+		}
+		if ent.Caller.Function != "" {
+			origin = append(origin, zap.String("log.origin.function", ent.Caller.Function))
+		}
+		fields = append(origin, fields...)
+	}
+	return enc.Encoder.EncodeEntry(ent, fields)
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Built-in formats of Config.Format.
+const (
+	FormatKV      = "kv"
+	FormatCommon  = "common"
+	FormatConsole = "console"
+	FormatJSON    = "json"
+	FormatLogfmt  = "logfmt"
+	FormatGELF    = "gelf"
+	FormatECS     = "ecs"
+)
+
+// EncoderFactory creates the encoder of a format. cfg holds the keys and
+// encoders shared by the formats, which the factory may change. Its level
+// encoder writes capital levels, colored when the output is a terminal.
+type EncoderFactory func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error)
+
+var (
+	encoderMu    sync.RWMutex
+	encoderTypes = map[string]EncoderFactory{}
+)
+
+func init() {
+	RegisterEncoder(FormatKV, func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewkvEncoder(cfg), nil
+	})
+	RegisterEncoder(FormatCommon, func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return NewCommonEncoder(cfg), nil
+	})
+	RegisterEncoder(FormatConsole, func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return zapcore.NewConsoleEncoder(cfg), nil
+	})
+	RegisterEncoder(FormatJSON, func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		cfg.EncodeLevel = zapcore.LowercaseLevelEncoder
+		return zapcore.NewJSONEncoder(cfg), nil
+	})
+	RegisterEncoder(FormatLogfmt, newLogfmtEncoder)
+	RegisterEncoder(FormatGELF, newGELFEncoder)
+	RegisterEncoder(FormatECS, newECSEncoder)
+}
+
+// RegisterEncoder makes a format available to Config.Format and
+// OutputConfig.Format. Registering an existing format replaces it.
+func RegisterEncoder(name string, factory EncoderFactory) {
+	encoderMu.Lock()
+	defer encoderMu.Unlock()
+	encoderTypes[name] = factory
+}
+
+// encoderRegistered reports whether format names a registered format. The
+// empty format is the default, kv.
+func encoderRegistered(format string) bool {
+	if format == "" {
+		return true
+	}
+	encoderMu.RLock()
+	defer encoderMu.RUnlock()
+	_, ok := encoderTypes[format]
+	return ok
+}
+
+// configurableEncoder is implemented by the built-in encoders that take
+// settings of Config beyond the EncoderConfig, such as Layout or App.
+type configurableEncoder interface {
+	configure(conf *Config) error
+}
+
+func newEncoderConfig(color bool) zapcore.EncoderConfig {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05,000")
+	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	if color {
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+	encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
+	encoderConfig.EncodeDuration = zapcore.SecondsDurationEncoder
+	encoderConfig.EncodeName = zapcore.FullNameEncoder
+	encoderConfig.ConsoleSeparator = " "
+	encoderConfig.LineEnding = zapcore.DefaultLineEnding
+	encoderConfig.MessageKey = "message"
+	encoderConfig.StacktraceKey = "stacktrace"
+	encoderConfig.CallerKey = "caller"
+	encoderConfig.FunctionKey = "function"
+	return encoderConfig
+}
+
+// newEncoder returns the encoder of format. color turns on colored levels,
+// for outputs that are terminals.
+func newEncoder(format string, conf *Config, color bool) (zapcore.Encoder, error) {
+	if format == "" {
+		format = FormatKV
+	}
+	encoderMu.RLock()
+	factory, ok := encoderTypes[format]
+	encoderMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("log: unknown format %q", format)
+	}
+	cfg := newEncoderConfig(color)
+	if conf.Caller.Path == CallerFull {
+		cfg.EncodeCaller = zapcore.FullCallerEncoder
+	}
+	enc, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("log: create %s encoder: %w", format, err)
+	}
+	if c, ok := enc.(configurableEncoder); ok {
+		if err := c.configure(conf); err != nil {
+			return nil, err
+		}
+	}
+	return enc, nil
+}
@@ -0,0 +1,181 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// plainErrorFormats list the causes of an error as messages, e.g.
+// errorCauses=[a,b]; the other formats write them as objects.
+var plainErrorFormats = map[string]bool{
+	FormatKV:     true,
+	FormatCommon: true,
+	FormatLogfmt: true,
+}
+
+// errorCore expands the error fields of every entry: an error field "error"
+// is written as error=<message>, errorVerbose=<%+v> for errors that format
+// differently with %+v, e.g. with a stack, and errorCauses=[...] with the
+// errors it wraps, see errorCauses. The ecs format writes the field "error"
+// as the ECS error object instead: error.message, error.type, error.verbose
+// and error.causes, with causes of message, type and causes.
+type errorCore struct {
+	zapcore.Core
+	plain bool
+	ecs   bool
+}
+
+// newErrorCore returns an errorCore expanding the error fields of core as
+// format writes them.
+func newErrorCore(core zapcore.Core, format string) zapcore.Core {
+	return &errorCore{Core: core, plain: plainErrorFormats[format], ecs: format == FormatECS}
+}
+
+func (c *errorCore) With(fields []zapcore.Field) zapcore.Core {
+	return &errorCore{Core: c.Core.With(c.fields(fields)), plain: c.plain, ecs: c.ecs}
+}
+
+func (c *errorCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *errorCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.fields(fields))
+}
+
+// fields returns fields with the error fields expanded, fields itself if it
+// holds no error.
+func (c *errorCore) fields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		err, ok := f.Interface.(error)
+		if f.Type != zapcore.ErrorType || !ok {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		out = append(out, zap.Inline(errorFields{key: f.Key, err: err, plain: c.plain, ecs: c.ecs && f.Key == "error"}))
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// errorFields adds an error, its verbose form and its causes to an object.
+type errorFields struct {
+	key   string
+	err   error
+	plain bool
+	// cause is set for the objects of errorCauses, which leave out the
+	// verbose form repeating the one of the error
+	cause bool
+	// chained is set for the causes along an errors.Unwrap chain, followed
+	// by their own causes in the list
+	chained bool
+	// ecs writes the ECS error object, see errorCore
+	ecs bool
+}
+
+// keys returns the keys of the message, verbose form, causes and, for ECS
+// only, type of the error.
+func (e errorFields) keys() (msg, verbose, causes, typ string) {
+	switch {
+	case e.ecs && e.cause:
+		return "message", "", "causes", "type"
+	case e.ecs:
+		return "error.message", "error.verbose", "error.causes", "error.type"
+	}
+	return e.key, e.key + "Verbose", e.key + "Causes", ""
+}
+
+func (e errorFields) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	msgKey, verboseKey, causesKey, typeKey := e.keys()
+	msg := errorMessage(e.err)
+	enc.AddString(msgKey, msg)
+	if typeKey != "" {
+		enc.AddString(typeKey, fmt.Sprintf("%T", e.err))
+	}
+	if _, ok := e.err.(fmt.Formatter); ok && !e.cause {
+		if verbose := fmt.Sprintf("%+v", e.err); verbose != msg {
+			enc.AddString(verboseKey, verbose)
+		}
+	}
+	if e.chained && unwrapMulti(e.err) == nil {
+		return nil
+	}
+	if causes, chained := errorCauses(e.err); len(causes) > 0 {
+		return enc.AddArray(causesKey, causeArray{causes: causes, plain: e.plain, chained: chained, ecs: e.ecs})
+	}
+	return nil
+}
+
+type causeArray struct {
+	causes  []error
+	plain   bool
+	chained bool
+	ecs     bool
+}
+
+func (a causeArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, err := range a.causes {
+		if a.plain {
+			enc.AppendString(errorMessage(err))
+			continue
+		}
+		if err := enc.AppendObject(errorFields{key: "error", err: err, cause: true, chained: a.chained, ecs: a.ecs}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errorCauses returns the errors err wraps: the errors joined in err, by
+// errors.Join or a multierr style Errors method, or else the errors.Unwrap
+// chain down to the root cause, stopping at a joined error. chained reports
+// the latter.
+func errorCauses(err error) (causes []error, chained bool) {
+	if multi := unwrapMulti(err); multi != nil {
+		return multi, false
+	}
+	for cause := errors.Unwrap(err); cause != nil; cause = errors.Unwrap(cause) {
+		causes = append(causes, cause)
+		if unwrapMulti(cause) != nil {
+			break
+		}
+	}
+	return causes, true
+}
+
+// unwrapMulti returns the errors joined in err, nil if err joins none.
+func unwrapMulti(err error) []error {
+	switch err := err.(type) {
+	case interface{ Unwrap() []error }:
+		return err.Unwrap()
+	case interface{ Errors() []error }:
+		return err.Errors()
+	}
+	return nil
+}
+
+// errorMessage returns err.Error(), recovering like zap from the panic of a
+// nil pointer receiver.
+func errorMessage(err error) (msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg = fmt.Sprintf("<PANIC=%v>", r)
+		}
+	}()
+	return err.Error()
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// stackError formats with a stack on %+v, like pkg/errors.
+type stackError struct{ msg string }
+
+func (e stackError) Error() string { return e.msg }
+
+func (e stackError) Format(s fmt.State, verb rune) {
+	fmt.Fprint(s, e.msg)
+	if s.Flag('+') {
+		fmt.Fprint(s, "\nmain.load\n\tmain.go:12")
+	}
+}
+
+func TestErrorFields(t *testing.T) {
+	root := errors.New("denied")
+	chain := fmt.Errorf("read config: %w", fmt.Errorf("open app.yaml: %w", root))
+	joined := errors.Join(errors.New("disk full"), fmt.Errorf("flush: %w", root))
+
+	kvSink, kv := testSink(t)
+	jsonSink, js := testSink(t)
+	gelfSink, gelf := testSink(t)
+	ecsSink, ecs := testSink(t)
+	l, err := New(&Config{Level: "info", Outputs: []OutputConfig{
+		{Type: kvSink, Format: FormatKV},
+		{Type: jsonSink, Format: FormatJSON},
+		{Type: gelfSink, Format: FormatGELF},
+		{Type: ecsSink, Format: FormatECS},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	l.Errorw("chain", "error", chain)
+	l.With("error", joined).Error("joined")
+	l.Errorw("verbose", "error", stackError{"boom"})
+
+	lines := strings.Split(strings.TrimSpace(kv.String()), "\n")
+	for i, want := range []string{
+		`error="read config: open app.yaml: denied" errorCauses=["open app.yaml: denied",denied] message=chain`,
+		`error="disk full\nflush: denied" errorCauses=["disk full","flush: denied"] message=joined`,
+		`error=boom errorVerbose="boom\nmain.load\n\tmain.go:12" message=verbose`,
+	} {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("kv line %d:\n got: %s\nwant suffix: %s", i, lines[i], want)
+		}
+	}
+
+	got := errorFieldEntries(t, js.String())
+	for i, want := range []string{
+		`{"error":"read config: open app.yaml: denied","errorCauses":[{"error":"open app.yaml: denied"},{"error":"denied"}]}`,
+		`{"error":"disk full\nflush: denied","errorCauses":[{"error":"disk full"},{"error":"flush: denied","errorCauses":[{"error":"denied"}]}]}`,
+		`{"error":"boom","errorVerbose":"boom\nmain.load\n\tmain.go:12"}`,
+	} {
+		if got[i] != want {
+			t.Errorf("json entry %d:\n got: %s\nwant: %s", i, got[i], want)
+		}
+	}
+
+	got = errorFieldEntries(t, gelf.String())
+	for i, want := range []string{
+		`{"_error":"read config: open app.yaml: denied","_errorCauses":[{"error":"open app.yaml: denied"},{"error":"denied"}]}`,
+		`{"_error":"disk full\nflush: denied","_errorCauses":[{"error":"disk full"},{"error":"flush: denied","errorCauses":[{"error":"denied"}]}]}`,
+		`{"_error":"boom","_errorVerbose":"boom\nmain.load\n\tmain.go:12"}`,
+	} {
+		if got[i] != want {
+			t.Errorf("gelf entry %d:\n got: %s\nwant: %s", i, got[i], want)
+		}
+	}
+
+	got = errorFieldEntries(t, ecs.String())
+	for i, want := range []string{
+		`{"error.causes":[{"message":"open app.yaml: denied","type":"*fmt.wrapError"},{"message":"denied","type":"*errors.errorString"}],` +
+			`"error.message":"read config: open app.yaml: denied","error.type":"*fmt.wrapError"}`,
+		`{"error.causes":[{"message":"disk full","type":"*errors.errorString"},` +
+			`{"causes":[{"message":"denied","type":"*errors.errorString"}],"message":"flush: denied","type":"*fmt.wrapError"}],` +
+			`"error.message":"disk full\nflush: denied","error.type":"*errors.joinError"}`,
+		`{"error.message":"boom","error.type":"log.stackError","error.verbose":"boom\nmain.load\n\tmain.go:12"}`,
+	} {
+		if got[i] != want {
+			t.Errorf("ecs entry %d:\n got: %s\nwant: %s", i, got[i], want)
+		}
+	}
+}
+
+func TestErrorFieldsDefaultFormat(t *testing.T) {
+	l, buf := newBufferLogger(t, &Config{})
+	l.Errorw("err", "error", fmt.Errorf("wrap: %w", errors.New("root")))
+	if want := `error="wrap: root" errorCauses=[root] message=err`; !strings.HasSuffix(strings.TrimSpace(buf.String()), want) {
+		t.Errorf("got %q, want suffix %q", buf.String(), want)
+	}
+}
+
+// errorFieldEntries decodes the JSON entries of out and returns their keys
+// holding "error", re-encoded with sorted keys.
+func errorFieldEntries(t *testing.T, out string) []string {
+	t.Helper()
+	var entries []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var e map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		for k := range e {
+			if !strings.Contains(k, "error") {
+				delete(e, k)
+			}
+		}
+		b, _ := json.Marshal(e)
+		entries = append(entries, string(b))
+	}
+	return entries
+}
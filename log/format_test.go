@@ -0,0 +1,153 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func encodeFormat(t *testing.T, format string, ent zapcore.Entry, with []zapcore.Field, fields ...zapcore.Field) string {
+	t.Helper()
+	enc, err := newEncoder(format, &Config{App: "myapp"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range with {
+		f.AddTo(enc)
+	}
+	buf, err := enc.EncodeEntry(ent, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.Free()
+	return buf.String()
+}
+
+func TestFormats(t *testing.T) {
+	ent := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC),
+		LoggerName: "push",
+		Message:    "push failed",
+		Caller:     zapcore.NewEntryCaller(0, "/src/gopkg/prometheus/push.go", 42, true),
+	}
+	user := kvTestUser{Name: "jane", Addr: kvTestAddr{City: "Oslo", Tags: []string{"home", "main"}}}
+	with := []zapcore.Field{zap.String("req", "r1")}
+	fields := []zapcore.Field{zap.Int("code", 503), zap.Object("user", user)}
+
+	t.Run("logfmt", func(t *testing.T) {
+		got := encodeFormat(t, FormatLogfmt, ent, with, fields...)
+		want := `time=2026-10-18T08:00:00.000Z level=warn logger=push caller=prometheus/push.go:42 msg="push failed" ` +
+			`req=r1 code=503 user.name=jane user.addr.city=Oslo user.addr.tags="[home,main]"` + "\n"
+		if got != want {
+			t.Errorf("got  %q\nwant %q", got, want)
+		}
+
+		got = encodeFormat(t, FormatLogfmt, zapcore.Entry{Message: "m"}, nil,
+			zap.Objects("users", []kvTestUser{{Name: "a"}}), zap.Any("map", map[string]int{"k": 1}))
+		want = `level=info msg=m users="[{name=a addr={city=\"\" tags=[]}}]" map="{\"k\":1}"` + "\n"
+		if got != want {
+			t.Errorf("got  %q\nwant %q", got, want)
+		}
+	})
+
+	t.Run("gelf", func(t *testing.T) {
+		var got map[string]interface{}
+		line := encodeFormat(t, FormatGELF, ent, with, append(fields, zap.String("id", "p1"))...)
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		host, _ := os.Hostname()
+		want := map[string]interface{}{
+			"version":       "1.1",
+			"host":          host,
+			"short_message": "push failed",
+			"timestamp":     float64(ent.Time.Unix()),
+			"level":         float64(4),
+			"_app":          "myapp",
+			"_logger":       "push",
+			"_caller":       "prometheus/push.go:42",
+			"_req":          "r1",
+			"_code":         float64(503),
+			"_id_":          "p1",
+		}
+		for k, v := range want {
+			if got[k] != v {
+				t.Errorf("%s: got %#v, want %#v", k, got[k], v)
+			}
+		}
+		if u, ok := got["_user"].(map[string]interface{}); !ok || u["name"] != "jane" {
+			t.Errorf("_user: got %#v", got["_user"])
+		}
+		if _, ok := got["_id"]; ok {
+			t.Error("wrote the reserved field _id")
+		}
+	})
+
+	t.Run("ecs", func(t *testing.T) {
+		var got map[string]interface{}
+		line := encodeFormat(t, FormatECS, ent, with, fields...)
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		want := map[string]interface{}{
+			"@timestamp":           "2026-10-18T08:00:00.000Z",
+			"log.level":            "warn",
+			"log.logger":           "push",
+			"log.origin.file.name": "prometheus/push.go",
+			"log.origin.file.line": float64(42),
+			"message":              "push failed",
+			"ecs.version":          ecsVersion,
+			"service.name":         "myapp",
+			"process.pid":          float64(os.Getpid()),
+			"req":                  "r1",
+			"code":                 float64(503),
+		}
+		for k, v := range want {
+			if got[k] != v {
+				t.Errorf("%s: got %#v, want %#v", k, got[k], v)
+			}
+		}
+	})
+
+	if _, err := newEncoder("xml", &Config{}, false); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if _, err := newEncoder("", &Config{}, false); err != nil {
+		t.Errorf("empty format: %v", err)
+	}
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("house", func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		cfg.TimeKey = ""
+		cfg.CallerKey = ""
+		return zapcore.NewConsoleEncoder(cfg), nil
+	})
+	defer func() {
+		encoderMu.Lock()
+		delete(encoderTypes, "house")
+		encoderMu.Unlock()
+	}()
+
+	line := encodeFormat(t, "house", zapcore.Entry{Level: zapcore.ErrorLevel, Message: "boom"}, nil, zap.Int("n", 1))
+	if want := "ERROR boom {\"n\": 1}\n"; line != want {
+		t.Errorf("got %q, want %q", line, want)
+	}
+
+	RegisterEncoder("broken", func(zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return nil, os.ErrInvalid
+	})
+	defer func() {
+		encoderMu.Lock()
+		delete(encoderTypes, "broken")
+		encoderMu.Unlock()
+	}()
+	if _, err := newEncoder("broken", &Config{}, false); err == nil {
+		t.Error("expected the error of the factory")
+	}
+}
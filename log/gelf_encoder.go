@@ -0,0 +1,120 @@
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// gelfVersion is the version of the GELF payload written by gelfEncoder.
+const gelfVersion = "1.1"
+
+// gelfEncoder writes entries as Graylog GELF 1.1 JSON objects. The time is a
+// unix timestamp in seconds, the level a syslog severity, and fields, which
+// GELF calls additional fields, have their names prefixed with '_'. A field
+// "id" is written as "_id_", GELF reserves "_id".
+type gelfEncoder struct {
+	zapcore.Encoder
+	// namespaced is set once a namespace is open, the keys inside of it
+	// are not prefixed
+	namespaced bool
+}
+
+func newGELFEncoder(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	cfg.MessageKey = "short_message"
+	cfg.StacktraceKey = "full_message"
+	cfg.TimeKey = "timestamp"
+	cfg.EncodeTime = gelfTimeEncoder
+	cfg.LevelKey = "level"
+	cfg.EncodeLevel = gelfLevelEncoder
+	cfg.NameKey = "_logger"
+	cfg.CallerKey = "_caller"
+	cfg.FunctionKey = "_function"
+
+	enc := zapcore.NewJSONEncoder(cfg)
+	enc.AddString("version", gelfVersion)
+	enc.AddString("host", hostname())
+	return &gelfEncoder{Encoder: enc}, nil
+}
+
+func (enc *gelfEncoder) configure(conf *Config) error {
+	enc.AddString("app", appName(conf.App))
+	return nil
+}
+
+func gelfTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendFloat64(float64(t.UnixNano()/int64(time.Millisecond)) / 1000)
+}
+
+func gelfLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt(syslogSeverity(l))
+}
+
+// key returns the name of an additional field.
+func (enc *gelfEncoder) key(key string) string {
+	switch {
+	case enc.namespaced:
+		return key
+	case key == "id":
+		return "_id_"
+	}
+	return "_" + key
+}
+
+func (enc *gelfEncoder) Clone() zapcore.Encoder {
+	return &gelfEncoder{Encoder: enc.Encoder.Clone(), namespaced: enc.namespaced}
+}
+
+// EncodeEntry adds the fields through enc, rather than the JSON encoder, so
+// the keys added by inline fields, which have none of their own, are
+// prefixed too.
+func (enc *gelfEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	if len(fields) == 0 {
+		return enc.Encoder.EncodeEntry(ent, nil)
+	}
+	c := enc.Clone().(*gelfEncoder)
+	for _, f := range fields {
+		f.AddTo(c)
+	}
+	return c.Encoder.EncodeEntry(ent, nil)
+}
+
+func (enc *gelfEncoder) AddArray(k string, v zapcore.ArrayMarshaler) error {
+	return enc.Encoder.AddArray(enc.key(k), v)
+}
+func (enc *gelfEncoder) AddObject(k string, v zapcore.ObjectMarshaler) error {
+	return enc.Encoder.AddObject(enc.key(k), v)
+}
+func (enc *gelfEncoder) AddReflected(k string, v interface{}) error {
+	return enc.Encoder.AddReflected(enc.key(k), v)
+}
+func (enc *gelfEncoder) AddBinary(k string, v []byte)     { enc.Encoder.AddBinary(enc.key(k), v) }
+func (enc *gelfEncoder) AddByteString(k string, v []byte) { enc.Encoder.AddByteString(enc.key(k), v) }
+func (enc *gelfEncoder) AddBool(k string, v bool)         { enc.Encoder.AddBool(enc.key(k), v) }
+func (enc *gelfEncoder) AddComplex128(k string, v complex128) {
+	enc.Encoder.AddComplex128(enc.key(k), v)
+}
+func (enc *gelfEncoder) AddComplex64(k string, v complex64) { enc.Encoder.AddComplex64(enc.key(k), v) }
+func (enc *gelfEncoder) AddDuration(k string, v time.Duration) {
+	enc.Encoder.AddDuration(enc.key(k), v)
+}
+func (enc *gelfEncoder) AddFloat64(k string, v float64) { enc.Encoder.AddFloat64(enc.key(k), v) }
+func (enc *gelfEncoder) AddFloat32(k string, v float32) { enc.Encoder.AddFloat32(enc.key(k), v) }
+func (enc *gelfEncoder) AddInt(k string, v int)         { enc.Encoder.AddInt(enc.key(k), v) }
+func (enc *gelfEncoder) AddInt64(k string, v int64)     { enc.Encoder.AddInt64(enc.key(k), v) }
+func (enc *gelfEncoder) AddInt32(k string, v int32)     { enc.Encoder.AddInt32(enc.key(k), v) }
+func (enc *gelfEncoder) AddInt16(k string, v int16)     { enc.Encoder.AddInt16(enc.key(k), v) }
+func (enc *gelfEncoder) AddInt8(k string, v int8)       { enc.Encoder.AddInt8(enc.key(k), v) }
+func (enc *gelfEncoder) AddString(k, v string)          { enc.Encoder.AddString(enc.key(k), v) }
+func (enc *gelfEncoder) AddTime(k string, v time.Time)  { enc.Encoder.AddTime(enc.key(k), v) }
+func (enc *gelfEncoder) AddUint(k string, v uint)       { enc.Encoder.AddUint(enc.key(k), v) }
+func (enc *gelfEncoder) AddUint64(k string, v uint64)   { enc.Encoder.AddUint64(enc.key(k), v) }
+func (enc *gelfEncoder) AddUint32(k string, v uint32)   { enc.Encoder.AddUint32(enc.key(k), v) }
+func (enc *gelfEncoder) AddUint16(k string, v uint16)   { enc.Encoder.AddUint16(enc.key(k), v) }
+func (enc *gelfEncoder) AddUint8(k string, v uint8)     { enc.Encoder.AddUint8(enc.key(k), v) }
+func (enc *gelfEncoder) AddUintptr(k string, v uintptr) { enc.Encoder.AddUintptr(enc.key(k), v) }
+func (enc *gelfEncoder) OpenNamespace(k string) {
+	enc.Encoder.OpenNamespace(enc.key(k))
+	enc.namespaced = true
+}
@@ -29,12 +29,41 @@ var (
 	encodeErrorBytes = []byte("encodeError")
 )
 
+// Object styles of the kv format, see Config.ObjectStyle.
+const (
+	// ObjectStyleBraces writes objects as key={child=value child2=value}.
+	ObjectStyleBraces = "braces"
+	// ObjectStyleFlatten writes objects as key.child=value key.child2=value.
+	ObjectStyleFlatten = "flatten"
+)
+
+// kvEncoder writes fields as space separated key=value pairs:
+//
+//   - arrays are written as key=[a,b,c]
+//   - objects and namespaces are written as key={child=value ...}, or as
+//     key.child=value with ObjectStyleFlatten. Objects inside arrays always
+//     use braces, e.g. key=[{a=1},{a=2}]
+//   - values without a native encoding are JSON encoded by reflection
 type kvEncoder struct {
 	*zapcore.EncoderConfig
 	buf            *buffer.Buffer
 	openNamespaces int
 	hostname       string
 
+	// flatten writes objects and namespaces outside of arrays as
+	// parent.child=value, see ObjectStyleFlatten
+	flatten bool
+	// prefix is prepended to keys while writing flattened objects
+	prefix string
+	// nesting holds '[' or '{' for every array or object being written
+	nesting []byte
+	// noSep is set right after a key or an opening bracket, where the next
+	// value needs no separator
+	noSep bool
+	// quoteNested writes arrays, objects and reflected values outside of
+	// arrays as quoted strings, for formats that can't nest values
+	quoteNested bool
+
 	// for encoding generic values by reflection
 	reflectBuf *buffer.Buffer
 	reflectEnc zapcore.ReflectedEncoder
@@ -52,23 +81,40 @@ func NewkvEncoder(cfg zapcore.EncoderConfig) *kvEncoder {
 		cfg.NewReflectedEncoder = defaultReflectedEncoder
 	}
 
-	hostname, err := os.Hostname()
-	if err != nil {
-		hostname = "unknown"
-	}
-
 	return &kvEncoder{
 		EncoderConfig: &cfg,
 		buf:           bufferpool.Get(),
-		hostname:      hostname,
+		hostname:      hostname(),
 	}
 }
 
+func (enc *kvEncoder) configure(conf *Config) error {
+	if conf.ObjectStyle == ObjectStyleFlatten {
+		enc.flatten = true
+	}
+	return nil
+}
+
+// hostname returns the name of the host, or "unknown".
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return name
+}
+
 func (enc kvEncoder) Clone() zapcore.Encoder {
 	clone := _jsonPool.Get()
 	clone.EncoderConfig = enc.EncoderConfig
 	// clone.spaced = enc.spaced
 	clone.openNamespaces = enc.openNamespaces
+	clone.hostname = enc.hostname
+	clone.flatten = enc.flatten
+	clone.prefix = enc.prefix
+	clone.nesting = append(clone.nesting[:0], enc.nesting...)
+	clone.noSep = enc.noSep
+	clone.quoteNested = enc.quoteNested
 	clone.buf = bufferpool.Get()
 	clone.buf.Write(enc.buf.Bytes())
 	return clone
@@ -122,10 +168,19 @@ func (c kvEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buff
 	// Add any structured context.
 	c.writeContext(line, fields)
 
-	// Add the message itself.
+	// Add the message itself, as a pair quoted like the field values so that
+	// a message containing '=' or '"' parses back unambiguously.
 	if c.MessageKey != "" {
 		c.addSeparatorIfNecessary(line)
-		line.AppendString(ent.Message)
+		line.AppendString(c.MessageKey)
+		line.AppendByte('=')
+		if needsQuoting(ent.Message, false, utf8.ValidString) {
+			line.AppendByte('"')
+			safeAppendStringLike((*buffer.Buffer).AppendString, utf8.DecodeRuneInString, line, ent.Message)
+			line.AppendByte('"')
+		} else {
+			line.AppendString(ent.Message)
+		}
 	}
 
 	// If there's no stacktrace key, honor that; this allows users to force
@@ -139,15 +194,28 @@ func (c kvEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buff
 	return line, nil
 }
 
+// flattening reports whether objects are currently written flattened.
+func (enc *kvEncoder) flattening() bool {
+	return enc.flatten && len(enc.nesting) == 0
+}
+
 func (enc *kvEncoder) OpenNamespace(key string) {
+	enc.openNamespaces++
+	if enc.flattening() {
+		enc.prefix += key + "."
+		return
+	}
 	enc.addKey(key)
 	enc.buf.AppendByte('{')
-	enc.openNamespaces++
+	enc.noSep = true
 }
 
 func (enc *kvEncoder) closeOpenNamespaces() {
-	for i := 0; i < enc.openNamespaces; i++ {
-		enc.buf.AppendByte('}')
+	if !enc.flattening() {
+		for i := 0; i < enc.openNamespaces; i++ {
+			enc.buf.AppendByte('}')
+			enc.noSep = false
+		}
 	}
 	enc.openNamespaces = 0
 }
@@ -155,19 +223,10 @@ func (enc *kvEncoder) closeOpenNamespaces() {
 func (c kvEncoder) writeContext(line *buffer.Buffer, extra []zapcore.Field) {
 	context := c.Clone().(*kvEncoder)
 	defer func() {
-		// putJSONEncoder assumes the buffer is still used, but we write out the buffer so
-		// we can free it.
+		// putKVEncoder assumes the buffer is still used, but we write out the
+		// buffer so we can free it.
 		context.buf.Free()
-
-		if context.reflectBuf != nil {
-			context.reflectBuf.Free()
-		}
-		context.EncoderConfig = nil
-		context.buf = nil
-		context.openNamespaces = 0
-		context.reflectBuf = nil
-		context.reflectEnc = nil
-		_jsonPool.Put(context)
+		putKVEncoder(context)
 	}()
 
 	for i := range extra {
@@ -186,6 +245,25 @@ func (c kvEncoder) writeContext(line *buffer.Buffer, extra []zapcore.Field) {
 
 }
 
+// putKVEncoder resets enc and returns it to the pool. The buffer is left to
+// the caller, which either hands it out or frees it.
+func putKVEncoder(enc *kvEncoder) {
+	if enc.reflectBuf != nil {
+		enc.reflectBuf.Free()
+	}
+	enc.EncoderConfig = nil
+	enc.buf = nil
+	enc.openNamespaces = 0
+	enc.flatten = false
+	enc.prefix = ""
+	enc.nesting = enc.nesting[:0]
+	enc.noSep = false
+	enc.quoteNested = false
+	enc.reflectBuf = nil
+	enc.reflectEnc = nil
+	_jsonPool.Put(enc)
+}
+
 func (c kvEncoder) addSeparatorIfNecessary(line *buffer.Buffer) {
 	if line.Len() > 0 {
 		line.AppendString(c.ConsoleSeparator)
@@ -298,19 +376,39 @@ func (enc *kvEncoder) truncate() {
 
 func (enc *kvEncoder) addKey(key string) {
 	enc.addElementSeparator()
-	enc.safeAddString(key)
+	if enc.prefix != "" {
+		enc.appendKey(enc.prefix)
+	}
+	enc.appendKey(key)
 	enc.buf.AppendByte('=')
+	enc.noSep = true
 }
 
+// appendKey writes key with the characters a logfmt key can't hold, blanks,
+// '=', '"' and control characters, replaced by '_'.
+func (enc *kvEncoder) appendKey(key string) {
+	last := 0
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; c > ' ' && c != '=' && c != '"' && c != 0x7f {
+			continue
+		}
+		enc.buf.AppendString(key[last:i])
+		enc.buf.AppendByte('_')
+		last = i + 1
+	}
+	enc.buf.AppendString(key[last:])
+}
+
+// addElementSeparator separates the next key or value from the previous one:
+// with a comma inside arrays, with a space elsewhere.
 func (enc *kvEncoder) addElementSeparator() {
-	last := enc.buf.Len() - 1
-	if last < 0 {
+	if enc.buf.Len() == 0 || enc.noSep {
+		enc.noSep = false
 		return
 	}
-	switch enc.buf.Bytes()[last] {
-	case '{', '[', ':', ',', ' ':
-		return
-	default:
+	if n := len(enc.nesting); n > 0 && enc.nesting[n-1] == '[' {
+		enc.buf.AppendByte(',')
+	} else {
 		enc.buf.AppendByte(' ')
 	}
 }
@@ -346,11 +444,24 @@ func (enc *kvEncoder) AppendUint32(v uint32)                { enc.AppendUint64(u
 func (enc *kvEncoder) AppendUint16(v uint16)                { enc.AppendUint64(uint64(v)) }
 func (enc *kvEncoder) AppendUint8(v uint8)                  { enc.AppendUint64(uint64(v)) }
 func (enc *kvEncoder) AppendUintptr(v uintptr)              { enc.AppendUint64(uint64(v)) }
-func (enc *kvEncoder) AppendUint64(val uint64)              { enc.buf.AppendUint(val) }
-func (enc *kvEncoder) AppendBool(val bool)                  { enc.buf.AppendBool(val) }
-func (enc *kvEncoder) AppendInt64(val int64)                { enc.buf.AppendInt(val) }
+
+func (enc *kvEncoder) AppendUint64(val uint64) {
+	enc.addElementSeparator()
+	enc.buf.AppendUint(val)
+}
+
+func (enc *kvEncoder) AppendBool(val bool) {
+	enc.addElementSeparator()
+	enc.buf.AppendBool(val)
+}
+
+func (enc *kvEncoder) AppendInt64(val int64) {
+	enc.addElementSeparator()
+	enc.buf.AppendInt(val)
+}
 
 func (enc *kvEncoder) appendFloat(val float64, bitSize int) {
+	enc.addElementSeparator()
 	switch {
 	case math.IsNaN(val):
 		enc.buf.AppendString(`"NaN"`)
@@ -367,7 +478,7 @@ func (enc *kvEncoder) appendFloat(val float64, bitSize int) {
 // precision specifies the encoding precision for the real and imaginary
 // components of the complex number.
 func (enc *kvEncoder) appendComplex(val complex128, precision int) {
-	// enc.addElementSeparator()
+	enc.addElementSeparator()
 	// Cast to a platform-independent, fixed-size type.
 	r, i := float64(real(val)), float64(imag(val))
 	// enc.buf.AppendByte('"')
@@ -390,12 +501,18 @@ func (enc *kvEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
 }
 
 func (enc *kvEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
-	// enc.addElementSeparator()
-	// 	enc.buf.AppendByte('[')
-	// 	err := arr.MarshalLogArray(enc)
-	// 	enc.buf.AppendByte(']')
-	// 	return err
-	return enc.AppendReflected(arr)
+	if enc.quoting() {
+		return enc.appendQuoted(func() error { return enc.AppendArray(arr) })
+	}
+	enc.addElementSeparator()
+	enc.buf.AppendByte('[')
+	enc.nesting = append(enc.nesting, '[')
+	enc.noSep = true
+	err := arr.MarshalLogArray(enc)
+	enc.nesting = enc.nesting[:len(enc.nesting)-1]
+	enc.buf.AppendByte(']')
+	enc.noSep = false
+	return err
 }
 
 func (enc *kvEncoder) AddBinary(key string, val []byte) {
@@ -409,6 +526,10 @@ func (enc *kvEncoder) AddByteString(key string, val []byte) {
 
 func (enc *kvEncoder) AppendByteString(val []byte) {
 	enc.addElementSeparator()
+	if !needsQuoting(val, len(enc.nesting) > 0, utf8.Valid) {
+		enc.buf.AppendBytes(val)
+		return
+	}
 	enc.buf.AppendByte('"')
 	enc.safeAddByteString(val)
 	enc.buf.AppendByte('"')
@@ -431,9 +552,38 @@ func (enc *kvEncoder) AppendDuration(val time.Duration) {
 }
 
 func (enc *kvEncoder) AppendString(val string) {
-	// enc.buf.AppendByte('"')
+	enc.addElementSeparator()
+	if !needsQuoting(val, len(enc.nesting) > 0, utf8.ValidString) {
+		enc.buf.AppendString(val)
+		return
+	}
+	enc.buf.AppendByte('"')
 	enc.safeAddString(val)
-	// enc.buf.AppendByte('"')
+	enc.buf.AppendByte('"')
+}
+
+// needsQuoting reports whether a string value must be quoted to be parsed
+// back unambiguously, following logfmt: empty values and values containing
+// blanks, '=', '"', backslashes, control characters or invalid UTF-8 are
+// quoted. Values that could be taken for an array or object are quoted too,
+// as are values containing ',', '[', ']', '{' or '}' inside arrays and
+// objects.
+func needsQuoting[S []byte | string](s S, nested bool, valid func(S) bool) bool {
+	if len(s) == 0 || s[0] == '[' || s[0] == '{' {
+		return true
+	}
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c <= ' ', c == '=', c == '"', c == '\\', c == 0x7f:
+			return true
+		case nested && (c == ',' || c == '[' || c == ']' || c == '{' || c == '}'):
+			return true
+		case c >= utf8.RuneSelf:
+			ascii = false
+		}
+	}
+	return !ascii && !valid(s)
 }
 
 func (enc *kvEncoder) AppendTime(val time.Time) {
@@ -448,20 +598,37 @@ func (enc *kvEncoder) AppendTime(val time.Time) {
 }
 
 func (enc *kvEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
-	enc.addKey(key)
-	return enc.AppendObject(obj)
+	if !enc.flattening() {
+		enc.addKey(key)
+		return enc.AppendObject(obj)
+	}
+	// Namespaces opened by the object only apply to its own fields.
+	oldPrefix, oldNamespaces := enc.prefix, enc.openNamespaces
+	enc.prefix += key + "."
+	enc.openNamespaces = 0
+	err := obj.MarshalLogObject(enc)
+	enc.prefix, enc.openNamespaces = oldPrefix, oldNamespaces
+	return err
 }
 
 func (enc *kvEncoder) AppendObject(obj zapcore.ObjectMarshaler) error {
+	if enc.quoting() {
+		return enc.appendQuoted(func() error { return enc.AppendObject(obj) })
+	}
 	// Close ONLY new openNamespaces that are created during AppendObject().
-	old := enc.openNamespaces
+	oldPrefix, oldNamespaces := enc.prefix, enc.openNamespaces
+	enc.prefix = ""
 	enc.openNamespaces = 0
 	enc.addElementSeparator()
 	enc.buf.AppendByte('{')
+	enc.nesting = append(enc.nesting, '{')
+	enc.noSep = true
 	err := obj.MarshalLogObject(enc)
-	enc.buf.AppendByte('}')
 	enc.closeOpenNamespaces()
-	enc.openNamespaces = old
+	enc.nesting = enc.nesting[:len(enc.nesting)-1]
+	enc.buf.AppendByte('}')
+	enc.noSep = false
+	enc.prefix, enc.openNamespaces = oldPrefix, oldNamespaces
 	return err
 }
 
@@ -500,8 +667,7 @@ func (enc *kvEncoder) AddReflected(key string, obj interface{}) error {
 		return err
 	}
 	enc.addKey(key)
-	_, err = enc.buf.Write(valueBytes)
-	return err
+	return enc.appendReflectedBytes(valueBytes)
 }
 
 func (enc *kvEncoder) AppendReflected(val interface{}) error {
@@ -509,6 +675,35 @@ func (enc *kvEncoder) AppendReflected(val interface{}) error {
 	if err != nil {
 		return err
 	}
-	_, err = enc.buf.Write(valueBytes)
+	return enc.appendReflectedBytes(valueBytes)
+}
+
+func (enc *kvEncoder) appendReflectedBytes(valueBytes []byte) error {
+	if enc.quoting() {
+		enc.AppendByteString(valueBytes)
+		return nil
+	}
+	enc.addElementSeparator()
+	_, err := enc.buf.Write(valueBytes)
+	return err
+}
+
+// quoting reports whether the next array, object or reflected value is
+// written as a quoted string, see quoteNested.
+func (enc *kvEncoder) quoting() bool {
+	return enc.quoteNested && len(enc.nesting) == 0
+}
+
+// appendQuoted writes the value written by encode as a single string.
+func (enc *kvEncoder) appendQuoted(encode func() error) error {
+	outer, noSep := enc.buf, enc.noSep
+	enc.buf = bufferpool.Get()
+	enc.quoteNested = false
+	err := encode()
+	enc.quoteNested = true
+	value := enc.buf
+	enc.buf, enc.noSep = outer, noSep
+	enc.AppendByteString(value.Bytes())
+	value.Free()
 	return err
 }
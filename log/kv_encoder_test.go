@@ -0,0 +1,95 @@
+package log
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type kvTestAddr struct {
+	City string
+	Tags []string
+}
+
+func (a kvTestAddr) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("city", a.City)
+	return enc.AddArray("tags", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, t := range a.Tags {
+			arr.AppendString(t)
+		}
+		return nil
+	}))
+}
+
+type kvTestUser struct {
+	Name string
+	Addr kvTestAddr
+}
+
+func (u kvTestUser) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.Name)
+	return enc.AddObject("addr", u.Addr)
+}
+
+func encodeKVFields(t *testing.T, flatten bool, fields ...zapcore.Field) string {
+	t.Helper()
+	enc := NewkvEncoder(zapcore.EncoderConfig{})
+	enc.flatten = flatten
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	enc.closeOpenNamespaces()
+	return enc.buf.String()
+}
+
+func TestKvEncoderNesting(t *testing.T) {
+	user := kvTestUser{Name: "jane", Addr: kvTestAddr{City: "Oslo", Tags: []string{"home", "main"}}}
+	tests := []struct {
+		name    string
+		fields  []zapcore.Field
+		braces  string
+		flatten string
+	}{
+		{
+			name:    "array",
+			fields:  []zapcore.Field{zap.Ints("ids", []int{1, 2, 3}), zap.Strings("empty", nil)},
+			braces:  "ids=[1,2,3] empty=[]",
+			flatten: "ids=[1,2,3] empty=[]",
+		},
+		{
+			name:    "object",
+			fields:  []zapcore.Field{zap.Object("user", user), zap.Int("n", 1)},
+			braces:  "user={name=jane addr={city=Oslo tags=[home,main]}} n=1",
+			flatten: "user.name=jane user.addr.city=Oslo user.addr.tags=[home,main] n=1",
+		},
+		{
+			name:    "objects in array",
+			fields:  []zapcore.Field{zap.Objects("users", []kvTestUser{user, {Name: "joe"}})},
+			braces:  "users=[{name=jane addr={city=Oslo tags=[home,main]}},{name=joe addr={city=\"\" tags=[]}}]",
+			flatten: "users=[{name=jane addr={city=Oslo tags=[home,main]}},{name=joe addr={city=\"\" tags=[]}}]",
+		},
+		{
+			name:    "reflected",
+			fields:  []zapcore.Field{zap.Any("map", map[string]int{"k": 1}), zap.Reflect("ch", make(chan int)), zap.Int("n", 1)},
+			braces:  `map={"k":1} ch=encodeError n=1`,
+			flatten: `map={"k":1} ch=encodeError n=1`,
+		},
+		{
+			name:    "namespace",
+			fields:  []zapcore.Field{zap.Int("a", 1), zap.Namespace("ns"), zap.Int("b", 2), zap.Bools("c", []bool{true, false})},
+			braces:  "a=1 ns={b=2 c=[true,false]}",
+			flatten: "a=1 ns.b=2 ns.c=[true,false]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodeKVFields(t, false, tt.fields...); got != tt.braces {
+				t.Errorf("braces:\ngot  %q\nwant %q", got, tt.braces)
+			}
+			if got := encodeKVFields(t, true, tt.fields...); got != tt.flatten {
+				t.Errorf("flatten:\ngot  %q\nwant %q", got, tt.flatten)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseKV parses a line written by the kv format back into its fields.
+//
+// Scalar values are returned as strings, arrays as []interface{} and objects
+// as map[string]interface{}, flattened objects keep their dotted keys.
+// Values the encoder wrote as JSON, such as reflected maps, are decoded as
+// JSON. The message is returned under its key, e.g. "message". Words that
+// are not key=value pairs, like the time, level and caller before the
+// fields, are skipped.
+func ParseKV(line string) (map[string]interface{}, error) {
+	p := &kvParser{s: strings.TrimRight(line, "\r\n")}
+	fields := make(map[string]interface{})
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return fields, nil
+		}
+		key, ok := p.key()
+		if !ok {
+			p.skipWord()
+			continue
+		}
+		val, err := p.value(false)
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = val
+	}
+}
+
+type kvParser struct {
+	s   string
+	pos int
+}
+
+func (p *kvParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *kvParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *kvParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("log: parse kv at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *kvParser) skipSpace() {
+	for !p.eof() && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *kvParser) skipWord() {
+	for !p.eof() && p.s[p.pos] != ' ' {
+		p.pos++
+	}
+}
+
+// key reads "key=" and returns the key. If the next word is not a key it
+// reads nothing and reports false.
+func (p *kvParser) key() (string, bool) {
+	start := p.pos
+	for i := start; i < len(p.s); i++ {
+		switch c := p.s[i]; {
+		case c == '=':
+			if i == start {
+				return "", false
+			}
+			p.pos = i + 1
+			return p.s[start:i], true
+		case c <= ' ', c == '"', c == '[', c == ']', c == '{', c == '}', c == ',':
+			return "", false
+		}
+	}
+	return "", false
+}
+
+func (p *kvParser) value(nested bool) (interface{}, error) {
+	switch p.peek() {
+	case '"':
+		return p.quoted()
+	case '[', '{':
+		start := p.pos
+		var (
+			v   interface{}
+			err error
+		)
+		if p.peek() == '[' {
+			v, err = p.array()
+		} else {
+			v, err = p.object()
+		}
+		if err == nil {
+			return v, nil
+		}
+		// not in kv syntax, it may have been encoded by reflection
+		p.pos = start
+		if v, ok := p.json(); ok {
+			return v, nil
+		}
+		return nil, err
+	}
+	start := p.pos
+	for !p.eof() {
+		c := p.s[p.pos]
+		if c == ' ' || (nested && (c == ',' || c == ']' || c == '}')) {
+			break
+		}
+		p.pos++
+	}
+	return p.s[start:p.pos], nil
+}
+
+func (p *kvParser) quoted() (string, error) {
+	start := p.pos
+	for i := start + 1; i < len(p.s); i++ {
+		switch p.s[i] {
+		case '\\':
+			i++
+		case '"':
+			p.pos = i + 1
+			s, err := strconv.Unquote(p.s[start:p.pos])
+			if err != nil {
+				p.pos = start
+				return "", p.errorf("bad quoted value: %v", err)
+			}
+			return s, nil
+		}
+	}
+	return "", p.errorf("unterminated quoted value")
+}
+
+func (p *kvParser) array() ([]interface{}, error) {
+	p.pos++ // [
+	arr := []interface{}{}
+	if p.peek() == ']' {
+		p.pos++
+		return arr, nil
+	}
+	for {
+		v, err := p.value(true)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return arr, nil
+		default:
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+func (p *kvParser) object() (map[string]interface{}, error) {
+	p.pos++ // {
+	obj := map[string]interface{}{}
+	for {
+		p.skipSpace()
+		if p.peek() == '}' {
+			p.pos++
+			return obj, nil
+		}
+		key, ok := p.key()
+		if !ok {
+			return nil, p.errorf("expected key in object")
+		}
+		v, err := p.value(true)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = v
+		if c := p.peek(); c != ' ' && c != '}' {
+			return nil, p.errorf("expected ' ' or '}' in object")
+		}
+	}
+}
+
+func (p *kvParser) json() (interface{}, bool) {
+	dec := json.NewDecoder(strings.NewReader(p.s[p.pos:]))
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	p.pos += int(dec.InputOffset())
+	return v, true
+}
@@ -0,0 +1,129 @@
+package log
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseKVRoundTrip(t *testing.T) {
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.EncodeTime = zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05,000")
+	cfg.EncodeLevel = zapcore.CapitalLevelEncoder
+	cfg.EncodeCaller = zapcore.ShortCallerEncoder
+	cfg.ConsoleSeparator = " "
+
+	ent := zapcore.Entry{
+		Level:   zapcore.InfoLevel,
+		Time:    time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC),
+		Message: `request done a=b k="x`,
+		Caller:  zapcore.NewEntryCaller(0, "/src/main.go", 7, true),
+	}
+	user := kvTestUser{Name: "jane doe", Addr: kvTestAddr{City: "São Paulo", Tags: []string{"a,b", "[x]"}}}
+
+	tests := []struct {
+		name   string
+		fields []zapcore.Field
+		want   map[string]interface{}
+	}{
+		{
+			name: "strings",
+			fields: []zapcore.Field{
+				zap.String("plain", "value"),
+				zap.String("empty", ""),
+				zap.String("spaces", "hello world"),
+				zap.String("equals", "a=b"),
+				zap.String("quotes", `say "hi"`),
+				zap.String("newline", "line1\nline2\ttab"),
+				zap.String("backslash", `C:\temp`),
+				zap.String("unicode", "héllo"),
+				zap.String("brackets", "[not an array]"),
+				zap.ByteString("bytes", []byte("b y t e s")),
+				zap.String("key with space", "v"),
+			},
+			want: map[string]interface{}{
+				"plain":          "value",
+				"empty":          "",
+				"spaces":         "hello world",
+				"equals":         "a=b",
+				"quotes":         `say "hi"`,
+				"newline":        "line1\nline2\ttab",
+				"backslash":      `C:\temp`,
+				"unicode":        "héllo",
+				"brackets":       "[not an array]",
+				"bytes":          "b y t e s",
+				"key_with_space": "v",
+			},
+		},
+		{
+			name: "numbers",
+			fields: []zapcore.Field{
+				zap.Int("int", -3),
+				zap.Float64("float", 1.5),
+				zap.Bool("bool", true),
+				zap.Duration("duration", 1500*time.Millisecond),
+			},
+			want: map[string]interface{}{
+				"int":      "-3",
+				"float":    "1.5",
+				"bool":     "true",
+				"duration": "1.5",
+			},
+		},
+		{
+			name: "nested",
+			fields: []zapcore.Field{
+				zap.Strings("list", []string{"a b", "", "c"}),
+				zap.Object("user", user),
+				zap.Any("map", map[string]int{"k": 1}),
+			},
+			want: map[string]interface{}{
+				"list": []interface{}{"a b", "", "c"},
+				"user": map[string]interface{}{
+					"name": "jane doe",
+					"addr": map[string]interface{}{
+						"city": "São Paulo",
+						"tags": []interface{}{"a,b", "[x]"},
+					},
+				},
+				"map": map[string]interface{}{"k": float64(1)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := NewkvEncoder(cfg).EncodeEntry(ent, tt.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer buf.Free()
+
+			got, err := ParseKV(buf.String())
+			if err != nil {
+				t.Fatalf("parse %q: %v", buf.String(), err)
+			}
+			if msg := got[cfg.MessageKey]; msg != ent.Message {
+				t.Errorf("line %q: got message %q, want %q", buf.String(), msg, ent.Message)
+			}
+			delete(got, cfg.MessageKey)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("line %q\ngot  %#v\nwant %#v", buf.String(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKVErrors(t *testing.T) {
+	for _, line := range []string{
+		`k="unterminated`,
+		`k=[a,b`,
+		`k={a=1`,
+	} {
+		if _, err := ParseKV(line); err == nil {
+			t.Errorf("ParseKV(%q): expected an error", line)
+		}
+	}
+}
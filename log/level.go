@@ -0,0 +1,41 @@
+package log
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// GetLevel returns the current level of the global logger, e.g. "info".
+func GetLevel() string {
+	return level.Level().String()
+}
+
+// SetLevel changes the level of the global logger at runtime. The level name
+// is case-insensitive; an empty or unknown name is rejected and the level is
+// left unchanged.
+func SetLevel(lvl string) error {
+	if lvl == "" {
+		return errors.New("log: level is empty")
+	}
+	l, err := parseLevel(lvl)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(l)
+	return nil
+}
+
+// stepLevel moves the global level by delta, staying within debug and error.
+// A negative delta makes the logger more verbose.
+func stepLevel(delta int) zapcore.Level {
+	l := level.Level() + zapcore.Level(delta)
+	if l < zapcore.DebugLevel {
+		l = zapcore.DebugLevel
+	}
+	if l > zapcore.ErrorLevel {
+		l = zapcore.ErrorLevel
+	}
+	level.SetLevel(l)
+	return l
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchLevelSignals lets the global level be changed with signals:
+// SIGUSR1 makes the logger one level more verbose (e.g. info -> debug) and
+// SIGUSR2 one level less verbose. The returned function stops watching.
+func WatchLevelSignals() (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				delta := 1
+				if sig == syscall.SIGUSR1 {
+					delta = -1
+				}
+				lvl := stepLevel(delta)
+				globalLogger().Infow("log level changed", "signal", sig.String(), "level", lvl.String())
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+
+package log
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestWatchLevelSignals(t *testing.T) {
+	defer level.SetLevel(level.Level())
+	level.SetLevel(zapcore.InfoLevel)
+	stop := WatchLevelSignals()
+	defer stop()
+
+	for _, tt := range []struct {
+		sig  syscall.Signal
+		want string
+	}{
+		{syscall.SIGUSR1, "debug"},
+		{syscall.SIGUSR2, "info"},
+		{syscall.SIGUSR2, "warn"},
+	} {
+		if err := syscall.Kill(os.Getpid(), tt.sig); err != nil {
+			t.Fatal(err)
+		}
+		// the next signal is sent once this one is handled, none is left
+		// pending when the watcher stops
+		deadline := time.Now().Add(5 * time.Second)
+		for GetLevel() != tt.want {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: level %s, want %s", tt.sig, GetLevel(), tt.want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+}
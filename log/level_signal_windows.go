@@ -0,0 +1,8 @@
+//go:build windows
+
+package log
+
+// WatchLevelSignals is a no-op on windows, which has no SIGUSR1/SIGUSR2.
+func WatchLevelSignals() (stop func()) {
+	return func() {}
+}
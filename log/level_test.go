@@ -0,0 +1,54 @@
+package log
+
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSetLevel(t *testing.T) {
+	defer level.SetLevel(level.Level())
+
+	for _, tt := range []struct{ set, want string }{
+		{"debug", "debug"},
+		{"WARN", "warn"},
+		{"Error", "error"},
+	} {
+		if err := SetLevel(tt.set); err != nil {
+			t.Fatalf("SetLevel(%q): %v", tt.set, err)
+		}
+		if got := GetLevel(); got != tt.want {
+			t.Errorf("SetLevel(%q): level %q, want %q", tt.set, got, tt.want)
+		}
+	}
+
+	for _, lvl := range []string{"loud", ""} {
+		if err := SetLevel(lvl); err == nil {
+			t.Errorf("SetLevel(%q): expected an error", lvl)
+		}
+	}
+	if got := GetLevel(); got != "error" {
+		t.Errorf("an invalid level changed the level to %q", got)
+	}
+}
+
+func TestStepLevel(t *testing.T) {
+	defer level.SetLevel(level.Level())
+
+	for _, tt := range []struct {
+		from  zapcore.Level
+		delta int
+		want  zapcore.Level
+	}{
+		{zapcore.InfoLevel, 1, zapcore.WarnLevel},
+		{zapcore.InfoLevel, -1, zapcore.DebugLevel},
+		{zapcore.DebugLevel, -1, zapcore.DebugLevel},
+		{zapcore.ErrorLevel, 1, zapcore.ErrorLevel},
+		{zapcore.DebugLevel, 10, zapcore.ErrorLevel},
+	} {
+		level.SetLevel(tt.from)
+		if got := stepLevel(tt.delta); got != tt.want || level.Level() != tt.want {
+			t.Errorf("stepLevel(%d) from %s: got %s, level %s, want %s", tt.delta, tt.from, got, level.Level(), tt.want)
+		}
+	}
+}
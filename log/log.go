@@ -1,98 +1,227 @@
 package log
 
 import (
+	"sync/atomic"
+
 	"go.uber.org/zap"
 )
 
-var logger *zap.SugaredLogger
+var (
+	// logger and loggerNoCaller are the global logger, with and without the
+	// lookup of the caller, see globalLogger. They drop the entries until
+	// Init.
+	logger         = newZapLogger(globalCore, globalCore, true).Sugar()
+	loggerNoCaller = newZapLogger(globalCore, globalCore, false).Sugar()
+	// noCallerLookup is set by Reload when the config needs no caller
+	noCallerLookup atomic.Bool
+	// level is the level of the global logger, see SetLevel.
+	level = zap.NewAtomicLevel()
+)
 
-type Config struct {
-	Format   string
-	Level    string
-	FilePath string
-	Rotate   RotateConfig
+// globalLogger returns the global logger, looking up the caller only if the
+// config needs it.
+func globalLogger() *zap.SugaredLogger {
+	if noCallerLookup.Load() {
+		return loggerNoCaller
+	}
+	return logger
 }
 
+type Config struct {
+	// Format is one of kv (the default), common, console, json, logfmt,
+	// gelf and ecs, or a format added with RegisterEncoder.
+	Format string `yaml:"format" json:"format" mapstructure:"format"`
+	Level  string `yaml:"level" json:"level" mapstructure:"level"`
+	// Levels overrides Level for named loggers, keyed by logger name prefix,
+	// e.g. {"pushservice": "warn", "http": "debug"}. A prefix also matches
+	// its children, so "http" applies to "http.client" as well.
+	Levels   map[string]string `yaml:"levels" json:"levels" mapstructure:"levels"`
+	FilePath string            `yaml:"file_path" json:"file_path" mapstructure:"file_path"`
+	Rotate   RotateConfig      `yaml:"rotate" json:"rotate" mapstructure:"rotate"`
+	// ErrorFilePath is a file that receives the warn and above entries only,
+	// of the ones the level lets through, on top of the other outputs.
+	// ErrorRotate configures its rotation.
+	ErrorFilePath string       `yaml:"error_file_path" json:"error_file_path" mapstructure:"error_file_path"`
+	ErrorRotate   RotateConfig `yaml:"error_rotate" json:"error_rotate" mapstructure:"error_rotate"`
+	// OutputMode picks the outputs when Outputs is empty: OutputBoth
+	// (default), OutputFile or OutputConsole.
+	OutputMode string `yaml:"output_mode" json:"output_mode" mapstructure:"output_mode"`
+	// Console is the sink of the console output, SinkStdout (default) or
+	// SinkStderr.
+	Console string `yaml:"console" json:"console" mapstructure:"console"`
+	// Outputs lists where entries are written. When empty, entries go to
+	// the console and, if FilePath is set, to a rotating file, see
+	// OutputMode. ErrorFilePath is added to either.
+	Outputs []OutputConfig `yaml:"outputs" json:"outputs" mapstructure:"outputs"`
+	// Async makes the outputs write asynchronously, see AsyncConfig.
+	Async AsyncConfig `yaml:"async" json:"async" mapstructure:"async"`
+	// Sampling and RateLimit cut down repeated entries, see SamplingConfig
+	// and RateLimitConfig.
+	Sampling  SamplingConfig  `yaml:"sampling" json:"sampling" mapstructure:"sampling"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" json:"rate_limit" mapstructure:"rate_limit"`
+	// Redact masks sensitive field values, see RedactConfig.
+	Redact RedactConfig `yaml:"redact" json:"redact" mapstructure:"redact"`
+	// Metrics counts the entries by level and logger name in EntriesTotal.
+	Metrics bool `yaml:"metrics" json:"metrics" mapstructure:"metrics"`
+	// Ring also keeps the latest entries in memory, at the level of the
+	// logger, see NewRing.
+	Ring *Ring `yaml:"-" json:"-" mapstructure:"-"`
+	// Layout is the line layout of the common format, default
+	// DefaultCommonLayout. See NewCommonEncoderWithLayout for the tokens.
+	Layout string `yaml:"layout" json:"layout" mapstructure:"layout"`
+	// App is the {app} token of Layout, default DefaultCommonApp, and the
+	// app name of the gelf and ecs formats, default the program name.
+	App string `yaml:"app" json:"app" mapstructure:"app"`
+	// Caller sets whether entries carry their caller, and which ones carry a
+	// stacktrace, see CallerConfig.
+	Caller CallerConfig `yaml:"caller" json:"caller" mapstructure:"caller"`
+	// ObjectStyle is how the kv and common formats write nested objects:
+	// ObjectStyleBraces (default) or ObjectStyleFlatten.
+	ObjectStyle string `yaml:"object_style" json:"object_style" mapstructure:"object_style"`
+}
+
+// Output modes of Config.OutputMode.
+const (
+	// OutputBoth writes to the console and, if FilePath is set, to the file.
+	OutputBoth = "both"
+	// OutputFile writes to the file only, FilePath must be set.
+	OutputFile = "file"
+	// OutputConsole writes to the console only, even if FilePath is set.
+	OutputConsole = "console"
+)
+
+// OutputConfig configures a single output of the logger.
+type OutputConfig struct {
+	// Type is the sink type: stdout, stderr, file, syslog, tcp, udp or the
+	// name of a sink added with RegisterSink.
+	Type string `yaml:"type" json:"type" mapstructure:"type"`
+	// Format defaults to the one of Config.
+	Format string `yaml:"format" json:"format" mapstructure:"format"`
+	// Level filters the entries of this output on top of the level of the
+	// logger: an entry must pass both, so SetLevel and Config.Levels still
+	// apply to the output.
+	Level string `yaml:"level" json:"level" mapstructure:"level"`
+	// Path is the file path of a file sink, or the socket path of a syslog
+	// sink (default /dev/log).
+	Path string `yaml:"path" json:"path" mapstructure:"path"`
+	// Address is the host:port of a tcp or udp sink.
+	Address string `yaml:"address" json:"address" mapstructure:"address"`
+	// Tag is the syslog tag, default the program name.
+	Tag string `yaml:"tag" json:"tag" mapstructure:"tag"`
+	// Rotate configures the rotation of a file sink.
+	Rotate RotateConfig `yaml:"rotate" json:"rotate" mapstructure:"rotate"`
+}
+
+// RotateConfig configures the rotation of a log file. Zero values take the
+// RotateMaxSize, RotateMaxAge and RotateMaxBackups defaults, except as
+// noted on MaxBackups.
 type RotateConfig struct {
-	Compress   bool
-	MaxSize    int
-	MaxAge     int
-	MaxBackups int
+	// Mode is RotateSize (default), RotateHourly or RotateDaily.
+	Mode string `yaml:"mode" json:"mode" mapstructure:"mode"`
+	// Pattern is the file name of hourly and daily rotated files, in the
+	// directory of the file path. %Y, %m, %d and %H stand for the year,
+	// month, day and hour the file was started, e.g. "app-%Y-%m-%d.log".
+	// The default is the file name with the date, or date and hour, before
+	// its extension.
+	Pattern  string `yaml:"pattern" json:"pattern" mapstructure:"pattern"`
+	Compress bool   `yaml:"compress" json:"compress" mapstructure:"compress"`
+	// MaxSize is the size in megabytes at which the file is rotated, more
+	// than 100. With hourly and daily rotation it caps the files of a
+	// period, the next ones are numbered, e.g. app-2026-10-18.1.log, and 0
+	// means no cap.
+	MaxSize int `yaml:"max_size" json:"max_size" mapstructure:"max_size"`
+	// MaxAge is the number of days rotated files are kept, at least 3.
+	MaxAge int `yaml:"max_age" json:"max_age" mapstructure:"max_age"`
+	// MaxBackups is the number of rotated files kept, at least 3. With
+	// hourly and daily rotation, MaxAge or MaxBackups left at 0 is no limit,
+	// e.g. MaxAge alone keeps files by age only; the defaults apply when
+	// both are 0.
+	MaxBackups int `yaml:"max_backups" json:"max_backups" mapstructure:"max_backups"`
+}
+
+// Init builds the global logger from config, like New, and panics if config
+// is invalid. Calling it again replaces the outputs of the global logger, as
+// Reload does.
+func Init(config *Config) {
+	if err := Reload(config); err != nil {
+		panic(err)
+	}
 }
 
-func Init(config *Config) {
-	logger = NewSugaredLogger(config)
+// Sync flushes any buffered entries of the global logger, including the
+// ones queued by async outputs. Call it, or Close, before the process exits.
+func Sync() error {
+	return globalCore.Sync()
 }
 
 func Debug(msg string) {
-	logger.Debug(msg)
-	logger.Named("debug").Debug(msg)
+	globalLogger().Debug(msg)
 }
 
 func Debugf(msg string, args ...interface{}) {
-	logger.Debugf(msg, args...)
+	globalLogger().Debugf(msg, args...)
 }
 
 func Debugw(msg string, keysAndValues ...interface{}) {
-	logger.Debugw(msg, keysAndValues...)
+	globalLogger().Debugw(msg, keysAndValues...)
 }
 
 func Info(msg string) {
-	logger.Info(msg)
+	globalLogger().Info(msg)
 }
 
 func Infof(msg string, args ...interface{}) {
-	logger.Infof(msg, args...)
+	globalLogger().Infof(msg, args...)
 }
 
 func Infow(msg string, keysAndValues ...interface{}) {
-	logger.Infow(msg, keysAndValues...)
+	globalLogger().Infow(msg, keysAndValues...)
 }
 
 func Warn(msg string) {
-	logger.Warn(msg)
+	globalLogger().Warn(msg)
 }
 
 func Warnf(msg string, args ...interface{}) {
-	logger.Warnf(msg, args...)
+	globalLogger().Warnf(msg, args...)
 }
 
 func Warnw(msg string, keysAndValues ...interface{}) {
-	logger.Warnw(msg, keysAndValues...)
+	globalLogger().Warnw(msg, keysAndValues...)
 }
 
 func Error(msg string) {
-	logger.Error(msg)
+	globalLogger().Error(msg)
 }
 
 func Errorf(msg string, args ...interface{}) {
-	logger.Errorf(msg, args...)
+	globalLogger().Errorf(msg, args...)
 }
 
 func Errorw(msg string, keysAndValues ...interface{}) {
-	logger.Errorw(msg, keysAndValues...)
+	globalLogger().Errorw(msg, keysAndValues...)
 }
 
 func Fatal(args ...interface{}) {
-	logger.Fatal(args)
+	globalLogger().Fatal(args...)
 }
 
 func Fatalf(template string, args ...interface{}) {
-	logger.Fatalf(template, args...)
+	globalLogger().Fatalf(template, args...)
 }
 
 func Fatalw(msg string, keysAndValues ...interface{}) {
-	logger.Fatalw(msg, keysAndValues...)
+	globalLogger().Fatalw(msg, keysAndValues...)
 }
 
 func Panic(args ...interface{}) {
-	logger.Panic(args)
+	globalLogger().Panic(args...)
 }
 
 func Panicf(template string, args ...interface{}) {
-	logger.Panicf(template, args...)
+	globalLogger().Panicf(template, args...)
 }
 
 func Panicw(msg string, keysAndValues ...interface{}) {
-	logger.Panicw(msg, keysAndValues...)
+	globalLogger().Panicw(msg, keysAndValues...)
 }
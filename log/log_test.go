@@ -1,38 +1,162 @@
 package log
 
-import "testing"
-
-func TestKvEncoder(t *testing.T) {
-
-	log := NewSugaredLogger(&Config{
-		Format: "common",
-		Level:  "debug",
-	})
-
-	message := "this a log message"
-	log.Debug(message)
-	log.Debugf("%s", message)
-	log.Debugw(
-		message,
-		"int", 1,
-		"bool", true,
-		"string", "str",
-		"float", 1.1,
-		"complex", 1+1i,
-		"duration", 1,
-		"byte", []byte("byte"),
-		"int64", int64(1),
-		"int32", int32(1),
-		"int16", int16(1),
-		"float32", float32(1.1),
-		"float64", float64(1.1),
-		"slice", []int{1, 2, 3},
-		"map", map[string]string{"k": "v"},
-		"array", [3]int{1, 2, 3},
-		"interface", interface{}(1),
-		"chan", make(chan int),
-		"ptr", &message,
-		"func", func() {},
-		"struct", struct{}{},
-	)
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+type goldenStruct struct {
+	Name string
+	Tags []string
+}
+
+// goldenString is logged through a pointer.
+var goldenString = "pointed"
+
+type goldenStringer struct{}
+
+func (goldenStringer) String() string { return "stringer value" }
+
+// goldenFields logs every field type, one case per line of a golden file.
+var goldenFields = []struct {
+	name   string
+	fields []zapcore.Field
+}{
+	{"bool", []zapcore.Field{zap.Bool("bool", true)}},
+	{"int", []zapcore.Field{zap.Int("int", -1)}},
+	{"int64", []zapcore.Field{zap.Int64("int64", 1<<40)}},
+	{"int32", []zapcore.Field{zap.Int32("int32", 1<<20)}},
+	{"int16", []zapcore.Field{zap.Int16("int16", 1<<10)}},
+	{"int8", []zapcore.Field{zap.Int8("int8", -8)}},
+	{"uint", []zapcore.Field{zap.Uint("uint", 1)}},
+	{"uint64", []zapcore.Field{zap.Uint64("uint64", 1<<63)}},
+	{"uintptr", []zapcore.Field{zap.Uintptr("uintptr", 0xbeef)}},
+	{"float64", []zapcore.Field{zap.Float64("float64", 1.1)}},
+	{"float32", []zapcore.Field{zap.Float32("float32", 1.1)}},
+	{"complex128", []zapcore.Field{zap.Complex128("complex128", 1+1i)}},
+	{"complex64", []zapcore.Field{zap.Complex64("complex64", -1.5i)}},
+	{"string", []zapcore.Field{zap.String("string", "str")}},
+	{"string_quoted", []zapcore.Field{zap.String("string", `with "quotes" and spaces`)}},
+	{"string_newline", []zapcore.Field{zap.String("string", "line\nnext\ttab")}},
+	{"string_empty", []zapcore.Field{zap.String("string", "")}},
+	{"string_unicode", []zapcore.Field{zap.String("string", "héllo, 世界")}},
+	{"bytestring", []zapcore.Field{zap.ByteString("bytes", []byte("byte"))}},
+	{"binary", []zapcore.Field{zap.Binary("binary", []byte{0, 1, 2, 0xff})}},
+	{"duration", []zapcore.Field{zap.Duration("duration", 1500*time.Millisecond)}},
+	{"time", []zapcore.Field{zap.Time("time", time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC))}},
+	{"error", []zapcore.Field{zap.Error(errors.New("boom"))}},
+	{"named_error", []zapcore.Field{zap.NamedError("cause", errors.New("timeout"))}},
+	{"error_chain", []zapcore.Field{zap.Error(fmt.Errorf("read: %w", fmt.Errorf("open: %w", errors.New("denied"))))}},
+	{"error_joined", []zapcore.Field{zap.Error(errors.Join(errors.New("a"), errors.New("b")))}},
+	{"stringer", []zapcore.Field{zap.Stringer("stringer", goldenStringer{})}},
+	{"ints", []zapcore.Field{zap.Ints("ints", []int{1, 2, 3})}},
+	{"strings", []zapcore.Field{zap.Strings("strings", []string{"a", "b c"})}},
+	{"empty_array", []zapcore.Field{zap.Strings("strings", nil)}},
+	{"object", []zapcore.Field{zap.Object("user", kvTestUser{Name: "bob", Addr: kvTestAddr{City: "paris", Tags: []string{"x", "y"}}})}},
+	{"reflect_struct", []zapcore.Field{zap.Reflect("struct", goldenStruct{Name: "n", Tags: []string{"t"}})}},
+	{"reflect_map", []zapcore.Field{zap.Any("map", map[string]int{"a": 1, "b": 2})}},
+	{"reflect_slice", []zapcore.Field{zap.Any("slice", []interface{}{1, "two", 3.0})}},
+	{"reflect_nil", []zapcore.Field{zap.Any("nil", nil)}},
+	{"reflect_chan", []zapcore.Field{zap.Any("chan", make(chan int))}},
+	{"reflect_func", []zapcore.Field{zap.Any("func", func() {})}},
+	{"reflect_ptr", []zapcore.Field{zap.Reflect("ptr", &goldenStruct{Name: "p"})}},
+	{"string_ptr", []zapcore.Field{zap.Any("ptr", &goldenString)}},
+	{"skip", []zapcore.Field{zap.Skip()}},
+	{"namespace", []zapcore.Field{zap.Int("outer", 1), zap.Namespace("ns"), zap.Int("inner", 2)}},
+	{"several", []zapcore.Field{zap.String("a", "1"), zap.Int("b", 2), zap.Bool("c", false)}},
+}
+
+// goldenEncoders are the encoders whose output the golden files lock down,
+// with a fixed host and app name.
+var goldenEncoders = []struct {
+	name string
+	new  func(cfg zapcore.EncoderConfig) zapcore.Encoder
+}{
+	{"kv", func(cfg zapcore.EncoderConfig) zapcore.Encoder {
+		enc := NewkvEncoder(cfg)
+		enc.hostname = "host"
+		return enc
+	}},
+	{"kv_flatten", func(cfg zapcore.EncoderConfig) zapcore.Encoder {
+		enc := NewkvEncoder(cfg)
+		enc.hostname = "host"
+		enc.flatten = true
+		return enc
+	}},
+	{"common", func(cfg zapcore.EncoderConfig) zapcore.Encoder {
+		enc := NewCommonEncoder(cfg)
+		enc.hostname = "host"
+		enc.app = "app"
+		return enc
+	}},
+}
+
+// TestEncoderGolden compares the output of the kv and common encoders with
+// testdata/<encoder>.golden, error fields expanded as errorCore does. Run
+// go test -run TestEncoderGolden -update to rewrite the files after an
+// intended change, and review the diff.
+func TestEncoderGolden(t *testing.T) {
+	ent := zapcore.Entry{
+		Level:      zapcore.InfoLevel,
+		Time:       time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC),
+		LoggerName: "golden",
+		Message:    "message",
+		Caller:     zapcore.NewEntryCaller(0, "/src/gopkg/log/log_test.go", 42, true),
+	}
+	for _, e := range goldenEncoders {
+		t.Run(e.name, func(t *testing.T) {
+			var b strings.Builder
+			for _, c := range goldenFields {
+				fields := (&errorCore{plain: true}).fields(c.fields)
+				buf, err := e.new(newEncoderConfig(false)).EncodeEntry(ent, fields)
+				if err != nil {
+					t.Fatalf("%s: %v", c.name, err)
+				}
+				b.WriteString(c.name + ": " + buf.String())
+				buf.Free()
+			}
+			checkGolden(t, filepath.Join("testdata", e.name+".golden"), b.String())
+		})
+	}
+}
+
+func checkGolden(t *testing.T, path, got string) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run with -update to create it", err)
+	}
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(string(want), "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			t.Errorf("%s:%d:\n got: %s\nwant: %s", path, i+1, g, w)
+		}
+	}
 }
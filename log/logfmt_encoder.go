@@ -0,0 +1,85 @@
+package log
+
+import (
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// logfmtEncoder writes entries in strict logfmt: the time, level, logger,
+// caller and message are key=value pairs like the fields, and values never
+// nest. Objects are flattened to key.child=value and arrays are written as
+// quoted strings, e.g. ids="[1,2,3]".
+type logfmtEncoder struct {
+	*kvEncoder
+}
+
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	cfg.TimeKey = "time"
+	cfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	cfg.EncodeLevel = zapcore.LowercaseLevelEncoder
+	cfg.MessageKey = "msg"
+	enc := NewkvEncoder(cfg)
+	enc.flatten = true
+	enc.quoteNested = true
+	return &logfmtEncoder{kvEncoder: enc}, nil
+}
+
+func (enc logfmtEncoder) Clone() zapcore.Encoder {
+	return &logfmtEncoder{kvEncoder: enc.kvEncoder.Clone().(*kvEncoder)}
+}
+
+func (enc logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.kvEncoder.Clone().(*kvEncoder)
+	final.buf.Reset()
+	final.prefix = ""
+	final.noSep = false
+
+	if final.TimeKey != "" && !ent.Time.IsZero() {
+		final.AddTime(final.TimeKey, ent.Time)
+	}
+	if final.LevelKey != "" && final.EncodeLevel != nil {
+		final.addKey(final.LevelKey)
+		final.EncodeLevel(ent.Level, final)
+	}
+	if ent.LoggerName != "" && final.NameKey != "" {
+		nameEncoder := final.EncodeName
+		if nameEncoder == nil {
+			nameEncoder = zapcore.FullNameEncoder
+		}
+		final.addKey(final.NameKey)
+		nameEncoder(ent.LoggerName, final)
+	}
+	if ent.Caller.Defined {
+		if final.CallerKey != "" && final.EncodeCaller != nil {
+			final.addKey(final.CallerKey)
+			final.EncodeCaller(ent.Caller, final)
+		}
+		if final.FunctionKey != "" && ent.Caller.Function != "" {
+			final.AddString(final.FunctionKey, ent.Caller.Function)
+		}
+	}
+	if final.MessageKey != "" {
+		final.AddString(final.MessageKey, ent.Message)
+	}
+
+	// fields added with With, then the fields of the entry
+	if enc.buf.Len() > 0 {
+		final.addElementSeparator()
+		final.buf.Write(enc.buf.Bytes())
+	}
+	final.prefix = enc.prefix
+	for i := range fields {
+		fields[i].AddTo(final)
+	}
+	final.closeOpenNamespaces()
+
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.prefix = ""
+		final.AddString(final.StacktraceKey, ent.Stack)
+	}
+
+	final.buf.AppendString(final.LineEnding)
+	line := final.buf
+	putKVEncoder(final)
+	return line, nil
+}
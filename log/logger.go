@@ -0,0 +1,181 @@
+package log
+
+import (
+	"io"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Logger is a leveled, structured logger. Get the global one with L, or build
+// one from a Config with New. Libraries can take a *Logger instead of building
+// their own, and derive theirs with Named and With.
+type Logger struct {
+	sugar  *zap.SugaredLogger
+	closer io.Closer
+}
+
+// global and globalNoCaller are the global logger returned by L, see
+// globalLogger.
+var (
+	global         = &Logger{sugar: logger, closer: globalCore}
+	globalNoCaller = &Logger{sugar: loggerNoCaller, closer: globalCore}
+)
+
+// L returns the global logger. It follows Init and Reload, so it can be
+// stored before Init; until then it drops the entries. A logger stored, or
+// derived with With or Named, before a config disabling the caller keeps
+// looking the caller up, and leaves it out of the entries.
+func L() *Logger {
+	if noCallerLookup.Load() {
+		return globalNoCaller
+	}
+	return global
+}
+
+// WithCallerSkip returns the global logger reporting the caller n frames
+// above its own, see Logger.WithCallerSkip.
+func WithCallerSkip(n int) *Logger {
+	return L().WithCallerSkip(n)
+}
+
+// New returns a logger built from config, or the errors of
+// Config.Validate if config is invalid. The level of the logger is its own,
+// SetLevel only changes the level of the global logger.
+func New(config *Config) (*Logger, error) {
+	core, closer, err := buildCore(config, zap.NewAtomicLevelAt(toZapLevel(config.Level)))
+	if err != nil {
+		return nil, err
+	}
+	return &Logger{sugar: newZapLogger(core, closer, config.Caller.lookup()).Sugar(), closer: closer}, nil
+}
+
+// NewWithCore returns a logger writing to core, e.g. an observer core in
+// tests. Its Close only syncs core.
+func NewWithCore(core zapcore.Core) *Logger {
+	return &Logger{sugar: newZapLogger(core, nil, true).Sugar()}
+}
+
+// With returns a logger that adds the key-value pairs to every entry, see
+// zap.SugaredLogger.With.
+func (l *Logger) With(keysAndValues ...interface{}) *Logger {
+	return &Logger{sugar: l.sugar.With(keysAndValues...), closer: l.closer}
+}
+
+// Named returns a logger with name appended to its name, dot separated.
+// Config.Levels can set the level of named loggers.
+func (l *Logger) Named(name string) *Logger {
+	return &Logger{sugar: l.sugar.Named(name), closer: l.closer}
+}
+
+// WithCallerSkip returns a logger reporting the caller n frames above its
+// own. A library logging through helpers of its own skips their frames, so
+// the caller is the code calling the helpers, e.g.
+//
+//	var logger = log.WithCallerSkip(1)
+//
+//	func logf(format string, args ...interface{}) { logger.Infof(format, args...) }
+func (l *Logger) WithCallerSkip(n int) *Logger {
+	return &Logger{sugar: l.sugar.WithOptions(zap.AddCallerSkip(n)), closer: l.closer}
+}
+
+// Sugar returns the logger as a zap.SugaredLogger, reporting its caller.
+func (l *Logger) Sugar() *zap.SugaredLogger {
+	// the logger skips the frame of its own methods, which is not there when
+	// the zap logger is used directly
+	return l.sugar.Desugar().WithOptions(zap.AddCallerSkip(-1)).Sugar()
+}
+
+// Desugar returns the logger as a zap.Logger, reporting its caller, e.g. for
+// middleware built on zap.Logger.
+func (l *Logger) Desugar() *zap.Logger {
+	return l.Sugar().Desugar()
+}
+
+// Sync flushes any buffered entries of the logger.
+func (l *Logger) Sync() error {
+	return l.sugar.Sync()
+}
+
+// Close flushes and closes the outputs of the logger. The logger must not be
+// used after, until Init or Reload for the global logger.
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return l.Sync()
+	}
+	// write what the cores hold back, e.g. rate limit summaries
+	_ = l.Sync()
+	return l.closer.Close()
+}
+
+func (l *Logger) Debug(args ...interface{}) {
+	l.sugar.Debug(args...)
+}
+
+func (l *Logger) Debugf(template string, args ...interface{}) {
+	l.sugar.Debugf(template, args...)
+}
+
+func (l *Logger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.sugar.Debugw(msg, keysAndValues...)
+}
+
+func (l *Logger) Info(args ...interface{}) {
+	l.sugar.Info(args...)
+}
+
+func (l *Logger) Infof(template string, args ...interface{}) {
+	l.sugar.Infof(template, args...)
+}
+
+func (l *Logger) Infow(msg string, keysAndValues ...interface{}) {
+	l.sugar.Infow(msg, keysAndValues...)
+}
+
+func (l *Logger) Warn(args ...interface{}) {
+	l.sugar.Warn(args...)
+}
+
+func (l *Logger) Warnf(template string, args ...interface{}) {
+	l.sugar.Warnf(template, args...)
+}
+
+func (l *Logger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.sugar.Warnw(msg, keysAndValues...)
+}
+
+func (l *Logger) Error(args ...interface{}) {
+	l.sugar.Error(args...)
+}
+
+func (l *Logger) Errorf(template string, args ...interface{}) {
+	l.sugar.Errorf(template, args...)
+}
+
+func (l *Logger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.sugar.Errorw(msg, keysAndValues...)
+}
+
+func (l *Logger) Fatal(args ...interface{}) {
+	l.sugar.Fatal(args...)
+}
+
+func (l *Logger) Fatalf(template string, args ...interface{}) {
+	l.sugar.Fatalf(template, args...)
+}
+
+func (l *Logger) Fatalw(msg string, keysAndValues ...interface{}) {
+	l.sugar.Fatalw(msg, keysAndValues...)
+}
+
+func (l *Logger) Panic(args ...interface{}) {
+	l.sugar.Panic(args...)
+}
+
+func (l *Logger) Panicf(template string, args ...interface{}) {
+	l.sugar.Panicf(template, args...)
+}
+
+func (l *Logger) Panicw(msg string, keysAndValues ...interface{}) {
+	l.sugar.Panicw(msg, keysAndValues...)
+}
@@ -0,0 +1,52 @@
+package log
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGlobalLogger(t *testing.T) {
+	sink, buf := testSink(t)
+	// L can be stored before Init
+	l := L().Named("lib").With("id", 7)
+	Init(&Config{Level: "debug", Outputs: []OutputConfig{{Type: sink}}})
+	defer Init(&Config{})
+
+	Debug("once")
+	l.Infow("injected", "k", "v")
+	if n := strings.Count(buf.String(), "once"); n != 1 {
+		t.Errorf("Debug logged %d times, want once: %q", n, buf.String())
+	}
+	var line string
+	for _, l := range strings.Split(buf.String(), "\n") {
+		if strings.Contains(l, "injected") {
+			line = l
+		}
+	}
+	for _, want := range []string{"lib", "id=7", "k=v", "logger_test.go"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("missing %q in %q", want, line)
+		}
+	}
+
+	func() {
+		defer func() { recover() }()
+		Panic("a", "b")
+	}()
+	if !strings.Contains(buf.String(), "ab") || strings.Contains(buf.String(), "[a b]") {
+		t.Errorf("Panic did not format its args like fmt.Sprint: %q", buf.String())
+	}
+}
+
+func TestLoggerCaller(t *testing.T) {
+	l, buf := newBufferLogger(t, &Config{Level: "info"})
+
+	l.Info("method")
+	l.Sugar().Info("sugar")
+	l.Desugar().Info("desugar")
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !strings.Contains(line, "logger_test.go") {
+			t.Errorf("wrong caller in %q", line)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Package logtest helps tests check what code using package log logged.
+//
+//	func TestPush(t *testing.T) {
+//		logger := logtest.New(t)
+//		push(logger.Logger)
+//		logger.AssertLogged("warn", "push failed", "code", 503)
+//	}
+package logtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lunuan/gopkg/log"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Logger is a *log.Logger that keeps every entry it logs, at any level, so
+// tests can assert on them. The entries are also written to t.Log.
+type Logger struct {
+	*log.Logger
+	t    testing.TB
+	logs *observer.ObservedLogs
+}
+
+// New returns a logger capturing its entries for t.
+func New(t testing.TB) *Logger {
+	core, logs := observer.New(zapcore.DebugLevel)
+	tcore := zaptest.NewLogger(t, zaptest.Level(zapcore.DebugLevel)).Core()
+	return &Logger{
+		Logger: log.NewWithCore(zapcore.NewTee(core, tcore)),
+		t:      t,
+		logs:   logs,
+	}
+}
+
+// Entries returns the entries logged so far, with the fields of With and the
+// ones of the entry in LoggedEntry.Context.
+func (l *Logger) Entries() []observer.LoggedEntry {
+	return l.logs.All()
+}
+
+// Reset forgets the entries logged so far.
+func (l *Logger) Reset() {
+	l.logs.TakeAll()
+}
+
+// AssertLogged fails the test unless an entry was logged at level, with a
+// message containing msg and the given key-value pairs among its fields.
+// Field values are compared as formatted with %v, so 503 matches any integer
+// type and a time.Duration matches "1s".
+func (l *Logger) AssertLogged(level, msg string, keysAndValues ...interface{}) {
+	l.t.Helper()
+	if _, ok := l.find(level, msg, keysAndValues); !ok {
+		l.t.Errorf("no %s entry with message %q and fields %v, logged:\n%s", level, msg, keysAndValues, l.dump())
+	}
+}
+
+// AssertNotLogged fails the test if an entry was logged at level, with a
+// message containing msg and the given key-value pairs among its fields.
+func (l *Logger) AssertNotLogged(level, msg string, keysAndValues ...interface{}) {
+	l.t.Helper()
+	if e, ok := l.find(level, msg, keysAndValues); ok {
+		l.t.Errorf("unexpected %s entry %q with fields %v", level, e.Message, e.ContextMap())
+	}
+}
+
+func (l *Logger) find(level, msg string, keysAndValues []interface{}) (observer.LoggedEntry, bool) {
+	l.t.Helper()
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(strings.ToLower(level))); err != nil {
+		l.t.Fatalf("logtest: invalid level %q", level)
+	}
+	if len(keysAndValues)%2 != 0 {
+		l.t.Fatalf("logtest: odd number of key-value arguments %v", keysAndValues)
+	}
+	for _, e := range l.logs.All() {
+		if e.Level == lvl && strings.Contains(e.Message, msg) && hasFields(e.ContextMap(), keysAndValues) {
+			return e, true
+		}
+	}
+	return observer.LoggedEntry{}, false
+}
+
+func hasFields(fields map[string]interface{}, keysAndValues []interface{}) bool {
+	for i := 0; i < len(keysAndValues); i += 2 {
+		got, ok := fields[fmt.Sprint(keysAndValues[i])]
+		if !ok || fmt.Sprint(got) != fmt.Sprint(keysAndValues[i+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *Logger) dump() string {
+	var b strings.Builder
+	for _, e := range l.logs.All() {
+		fmt.Fprintf(&b, "\t%s %s %v\n", e.Level.CapitalString(), e.Message, e.ContextMap())
+	}
+	return b.String()
+}
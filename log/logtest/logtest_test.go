@@ -0,0 +1,46 @@
+package logtest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// recorder records the errors reported to it instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertLogged(t *testing.T) {
+	rec := &recorder{TB: t}
+	logger := New(rec)
+	logger.Named("push").With("app", "gopkg").Warnw("push failed", "code", 503, "took", time.Second)
+	logger.Info("done")
+
+	logger.AssertLogged("warn", "push failed", "code", 503, "took", "1s", "app", "gopkg")
+	logger.AssertLogged("INFO", "done")
+	logger.AssertNotLogged("error", "push failed")
+	if len(rec.errors) != 0 {
+		t.Fatalf("unexpected failures: %v", rec.errors)
+	}
+
+	logger.AssertLogged("warn", "push failed", "code", 500)
+	logger.AssertLogged("error", "push failed")
+	logger.AssertNotLogged("info", "done")
+	if len(rec.errors) != 3 {
+		t.Fatalf("got %d failures, want 3: %v", len(rec.errors), rec.errors)
+	}
+
+	if n := len(logger.Entries()); n != 2 {
+		t.Errorf("got %d entries, want 2", n)
+	}
+	logger.Reset()
+	if n := len(logger.Entries()); n != 0 {
+		t.Errorf("got %d entries after Reset", n)
+	}
+}
@@ -0,0 +1,41 @@
+package log
+
+import (
+	"github.com/lunuan/gopkg/prometheus"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap/zapcore"
+)
+
+// EntriesTotal counts the entries of the loggers with Config.Metrics set, as
+// log_entries_total{level,logger}. Register it to export it, e.g. with
+// prometheus.MustRegister or a push service, to alert on the error rate.
+var EntriesTotal = prometheus.NewCounterVec(prom.CounterOpts{
+	Name: "log_entries_total",
+	Help: "Number of log entries by level and logger name.",
+}, []string{"level", "logger"})
+
+// metricsCore counts the entries it is given in EntriesTotal. It counts them
+// when they are checked, so that entries dropped by sampling and rate limits
+// still count.
+type metricsCore struct {
+	zapcore.Core
+}
+
+// newMetricsCore wraps core if enabled, else returns core as is.
+func newMetricsCore(core zapcore.Core, enabled bool) zapcore.Core {
+	if !enabled {
+		return core
+	}
+	return &metricsCore{Core: core}
+}
+
+func (c *metricsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &metricsCore{Core: c.Core.With(fields)}
+}
+
+func (c *metricsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		EntriesTotal.WithLabelValues(ent.Level.String(), ent.LoggerName).Inc()
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,45 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	count := func(level, logger string) float64 {
+		return testutil.ToFloat64(EntriesTotal.WithLabelValues(level, logger))
+	}
+	errorsBefore, infosBefore := count("error", "metrics"), count("info", "metrics")
+
+	l, buf := newBufferLogger(t, &Config{Level: "info", Metrics: true,
+		RateLimit: RateLimitConfig{Window: time.Minute, Burst: 1}})
+	named := l.Named("metrics")
+	named.Debug("below the level")
+	for i := 0; i < 3; i++ {
+		named.Error("failed")
+	}
+	named.With("k", "v").Info("done")
+
+	if n := strings.Count(buf.String(), "failed"); n != 1 {
+		t.Fatalf("the rate limit let %d entries through, want 1", n)
+	}
+	if got := count("error", "metrics") - errorsBefore; got != 3 {
+		t.Errorf("counted %v error entries, want 3, rate limited ones included", got)
+	}
+	if got := count("info", "metrics") - infosBefore; got != 1 {
+		t.Errorf("counted %v info entries, want 1", got)
+	}
+	if got := count("debug", "metrics"); got != 0 {
+		t.Errorf("counted %v debug entries below the level", got)
+	}
+
+	// off by default
+	l, _ = newBufferLogger(t, &Config{Level: "info"})
+	l.Named("metrics").Error("not counted")
+	if got := count("error", "metrics") - errorsBefore; got != 3 {
+		t.Errorf("counted entries without Config.Metrics")
+	}
+}
@@ -0,0 +1,81 @@
+package log
+
+import (
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// allLevels enables every level; the wrapping nameLevelCore does the filtering.
+var allLevels = zap.LevelEnablerFunc(func(zapcore.Level) bool { return true })
+
+type nameLevel struct {
+	prefix string
+	level  zapcore.Level
+}
+
+// nameLevelCore applies per logger name level overrides. An entry whose logger
+// name equals a prefix, or starts with prefix + ".", is checked against that
+// prefix's level; the longest matching prefix wins. Any other entry is checked
+// against the default level.
+type nameLevelCore struct {
+	zapcore.Core
+	level     zapcore.LevelEnabler
+	overrides []nameLevel
+}
+
+// newNameLevelCore wraps core, which must enable every level the overrides may
+// let through. If there are no overrides core is returned as is.
+func newNameLevelCore(core zapcore.Core, level zapcore.LevelEnabler, levels map[string]string) zapcore.Core {
+	if len(levels) == 0 {
+		return core
+	}
+	overrides := make([]nameLevel, 0, len(levels))
+	for prefix, lvl := range levels {
+		overrides = append(overrides, nameLevel{prefix: prefix, level: toZapLevel(lvl)})
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		return len(overrides[i].prefix) > len(overrides[j].prefix)
+	})
+	return &nameLevelCore{Core: core, level: level, overrides: overrides}
+}
+
+func (c *nameLevelCore) levelFor(name string) zapcore.LevelEnabler {
+	for _, o := range c.overrides {
+		if name == o.prefix || strings.HasPrefix(name, o.prefix+".") {
+			return o.level
+		}
+	}
+	return c.level
+}
+
+// Enabled reports whether lvl may be logged by any logger name, the name is
+// only known once the entry is checked.
+func (c *nameLevelCore) Enabled(lvl zapcore.Level) bool {
+	if c.level.Enabled(lvl) {
+		return true
+	}
+	for _, o := range c.overrides {
+		if o.level.Enabled(lvl) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *nameLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &nameLevelCore{
+		Core:      c.Core.With(fields),
+		level:     c.level,
+		overrides: c.overrides,
+	}
+}
+
+func (c *nameLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levelFor(ent.LoggerName).Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
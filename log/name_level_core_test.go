@@ -0,0 +1,47 @@
+package log
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNameLevelCore(t *testing.T) {
+	obs, logs := observer.New(allLevels)
+	core := newNameLevelCore(obs, zapcore.InfoLevel, map[string]string{
+		"pushservice": "warn",
+		"http":        "debug",
+		"http.client": "error",
+	})
+	l := zap.New(core)
+
+	l.Debug("root debug")
+	l.Info("root info")
+	l.Named("pushservice").Info("push info")
+	l.Named("pushservice").Warn("push warn")
+	l.Named("pushservicex").Info("other info")
+	l.Named("http").Debug("http debug")
+	l.Named("http").Named("server").Debug("http server debug")
+	l.Named("http").Named("client").Warn("http client warn")
+	l.Named("http").Named("client").With(zap.Int("n", 1)).Error("http client error")
+
+	want := []string{
+		"root info",
+		"push warn",
+		"other info",
+		"http debug",
+		"http server debug",
+		"http client error",
+	}
+	got := logs.AllUntimed()
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Message != want[i] {
+			t.Errorf("entry %d: got %q, want %q", i, got[i].Message, want[i])
+		}
+	}
+}
@@ -0,0 +1,541 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const RedactMask = "******"
+
+// DefaultRedactKeys are field keys that usually hold secrets, a starting
+// point for RedactConfig.Keys.
+var DefaultRedactKeys = []string{
+	"password", "passwd", "secret", "authorization", "cookie",
+	"*token*", "*api_key*", "*apikey*", "*private_key*",
+}
+
+// RedactConfig masks sensitive values before they are encoded. It applies to
+// top-level fields, nested objects and arrays, and reflected values such as
+// structs and maps, whatever the format.
+type RedactConfig struct {
+	// Keys are the field keys whose values are masked, matched
+	// case-insensitively. A key containing * or ? is a glob, e.g. "*token*",
+	// and a key between slashes is a regexp, e.g. "/^x-.*-secret$/".
+	Keys []string `yaml:"keys" json:"keys" mapstructure:"keys"`
+	// Values are patterns masked inside string values: "jwt", "card",
+	// "email" or a regexp.
+	Values []string `yaml:"values" json:"values" mapstructure:"values"`
+	// Mask replaces the masked values, default RedactMask.
+	Mask string `yaml:"mask" json:"mask" mapstructure:"mask"`
+}
+
+var redactValuePatterns = map[string]string{
+	"jwt":   `eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,
+	"card":  `\b(?:\d[ -]?){12,18}\d\b`,
+	"email": `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+}
+
+type valuePattern struct {
+	re *regexp.Regexp
+	// luhn makes only numbers passing the Luhn check match, to tell card
+	// numbers from other long numbers
+	luhn bool
+}
+
+type redactor struct {
+	exact  map[string]struct{}
+	globs  []string
+	keyRes []*regexp.Regexp
+	values []valuePattern
+	mask   string
+}
+
+// newRedactor compiles conf. It returns nil if there is nothing to redact.
+func newRedactor(conf RedactConfig) (*redactor, error) {
+	if len(conf.Keys) == 0 && len(conf.Values) == 0 {
+		return nil, nil
+	}
+	r := &redactor{exact: make(map[string]struct{}), mask: conf.Mask}
+	if r.mask == "" {
+		r.mask = RedactMask
+	}
+	for _, key := range conf.Keys {
+		switch {
+		case len(key) > 1 && strings.HasPrefix(key, "/") && strings.HasSuffix(key, "/"):
+			re, err := regexp.Compile("(?i)" + key[1:len(key)-1])
+			if err != nil {
+				return nil, fmt.Errorf("log: redact key %s: %w", key, err)
+			}
+			r.keyRes = append(r.keyRes, re)
+		case strings.ContainsAny(key, "*?["):
+			if _, err := path.Match(key, ""); err != nil {
+				return nil, fmt.Errorf("log: redact key %s: %w", key, err)
+			}
+			r.globs = append(r.globs, strings.ToLower(key))
+		default:
+			r.exact[strings.ToLower(key)] = struct{}{}
+		}
+	}
+	for _, value := range conf.Values {
+		expr, builtin := redactValuePatterns[value]
+		if !builtin {
+			expr = value
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("log: redact value %s: %w", value, err)
+		}
+		r.values = append(r.values, valuePattern{re: re, luhn: value == "card"})
+	}
+	return r, nil
+}
+
+func (r *redactor) matchKey(key string) bool {
+	key = strings.ToLower(key)
+	if _, ok := r.exact[key]; ok {
+		return true
+	}
+	for _, glob := range r.globs {
+		if ok, _ := path.Match(glob, key); ok {
+			return true
+		}
+	}
+	for _, re := range r.keyRes {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) redactString(s string) string {
+	for _, p := range r.values {
+		if p.luhn {
+			s = p.re.ReplaceAllStringFunc(s, func(m string) string {
+				if luhnValid(m) {
+					return r.mask
+				}
+				return m
+			})
+			continue
+		}
+		s = p.re.ReplaceAllLiteralString(s, r.mask)
+	}
+	return s
+}
+
+// fields returns fields with sensitive values masked. fields itself is left
+// untouched.
+func (r *redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		redacted, changed := r.field(f)
+		if !changed {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		out = append(out, redacted)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func (r *redactor) field(f zapcore.Field) (zapcore.Field, bool) {
+	switch f.Type {
+	case zapcore.NamespaceType, zapcore.SkipType:
+		return f, false
+	}
+	if r.matchKey(f.Key) {
+		return zap.String(f.Key, r.mask), true
+	}
+	switch f.Type {
+	case zapcore.StringType:
+		if s := r.redactString(f.String); s != f.String {
+			f.String = s
+			return f, true
+		}
+	case zapcore.ByteStringType:
+		b := f.Interface.([]byte)
+		if s := r.redactString(string(b)); s != string(b) {
+			return zap.ByteString(f.Key, []byte(s)), true
+		}
+	case zapcore.ObjectMarshalerType, zapcore.InlineMarshalerType:
+		f.Interface = redactObject{obj: f.Interface.(zapcore.ObjectMarshaler), r: r}
+		return f, true
+	case zapcore.ArrayMarshalerType:
+		f.Interface = redactArray{arr: f.Interface.(zapcore.ArrayMarshaler), r: r}
+		return f, true
+	case zapcore.ReflectType:
+		f.Interface = r.reflected(f.Interface)
+		return f, true
+	case zapcore.StringerType:
+		str := stringerString(f.Interface.(fmt.Stringer))
+		if s := r.redactString(str); s != str {
+			return zap.String(f.Key, s), true
+		}
+	case zapcore.ErrorType:
+		// errorCore expands error fields before they get here, unless the
+		// redactor runs on its own
+		msg := errorMessage(f.Interface.(error))
+		if s := r.redactString(msg); s != msg {
+			return zap.NamedError(f.Key, errors.New(s)), true
+		}
+	}
+	return f, false
+}
+
+// stringerString returns s.String(), recovering like zap from the panic of a
+// nil pointer receiver.
+func stringerString(s fmt.Stringer) (str string) {
+	defer func() {
+		if r := recover(); r != nil {
+			str = fmt.Sprintf("<PANIC=%v>", r)
+		}
+	}()
+	return s.String()
+}
+
+// reflected masks a value that would be encoded by reflection. The value is
+// turned into its generic JSON form, so structs honor their json tags, with
+// numbers kept as json.Number. It is returned as is when nothing is masked.
+func (r *redactor) reflected(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return v
+	}
+	if generic, masked := r.generic(generic); masked {
+		return generic
+	}
+	return v
+}
+
+// generic masks a generic JSON value in place, reporting whether it masked
+// anything.
+func (r *redactor) generic(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		masked := false
+		for key, val := range v {
+			if r.matchKey(key) {
+				v[key], masked = r.mask, true
+			} else if val, ok := r.generic(val); ok {
+				v[key], masked = val, true
+			}
+		}
+		return v, masked
+	case []interface{}:
+		masked := false
+		for i := range v {
+			if val, ok := r.generic(v[i]); ok {
+				v[i], masked = val, true
+			}
+		}
+		return v, masked
+	case string:
+		s := r.redactString(v)
+		return s, s != v
+	default:
+		return v, false
+	}
+}
+
+func luhnValid(number string) bool {
+	sum, n := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
+
+// redactCore masks the fields of every entry before handing them to the
+// wrapped core.
+type redactCore struct {
+	zapcore.Core
+	r *redactor
+}
+
+func newRedactCore(core zapcore.Core, r *redactor) zapcore.Core {
+	if r == nil {
+		return core
+	}
+	return &redactCore{Core: core, r: r}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.r.fields(fields)), r: c.r}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.r.fields(fields))
+}
+
+type redactObject struct {
+	obj zapcore.ObjectMarshaler
+	r   *redactor
+}
+
+func (o redactObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return o.obj.MarshalLogObject(&redactObjectEncoder{ObjectEncoder: enc, r: o.r})
+}
+
+// MarshalJSON lets encoders that fall back to reflection encode the object.
+func (o redactObject) MarshalJSON() ([]byte, error) {
+	m := zapcore.NewMapObjectEncoder()
+	if err := o.MarshalLogObject(m); err != nil {
+		return nil, err
+	}
+	return json.Marshal(m.Fields)
+}
+
+type redactArray struct {
+	arr zapcore.ArrayMarshaler
+	r   *redactor
+}
+
+func (a redactArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	return a.arr.MarshalLogArray(&redactArrayEncoder{ArrayEncoder: enc, r: a.r})
+}
+
+// MarshalJSON lets encoders that fall back to reflection encode the array.
+func (a redactArray) MarshalJSON() ([]byte, error) {
+	s := &sliceArrayEncoder{}
+	if err := a.MarshalLogArray(s); err != nil {
+		return nil, err
+	}
+	return json.Marshal(s.elems)
+}
+
+// redactObjectEncoder masks the values added to an object by key, and the
+// strings by value pattern.
+type redactObjectEncoder struct {
+	zapcore.ObjectEncoder
+	r *redactor
+}
+
+// masked adds the mask under key if the key is sensitive.
+func (e *redactObjectEncoder) masked(key string) bool {
+	if !e.r.matchKey(key) {
+		return false
+	}
+	e.ObjectEncoder.AddString(key, e.r.mask)
+	return true
+}
+
+func (e *redactObjectEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	if e.masked(key) {
+		return nil
+	}
+	return e.ObjectEncoder.AddArray(key, redactArray{arr: arr, r: e.r})
+}
+
+func (e *redactObjectEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	if e.masked(key) {
+		return nil
+	}
+	return e.ObjectEncoder.AddObject(key, redactObject{obj: obj, r: e.r})
+}
+
+func (e *redactObjectEncoder) AddReflected(key string, value interface{}) error {
+	if e.masked(key) {
+		return nil
+	}
+	return e.ObjectEncoder.AddReflected(key, e.r.reflected(value))
+}
+
+func (e *redactObjectEncoder) AddString(key, value string) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddString(key, e.r.redactString(value))
+	}
+}
+
+func (e *redactObjectEncoder) AddByteString(key string, value []byte) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddByteString(key, []byte(e.r.redactString(string(value))))
+	}
+}
+
+func (e *redactObjectEncoder) AddBinary(key string, value []byte) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddBinary(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddBool(key string, value bool) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddBool(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddComplex128(key string, value complex128) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddComplex128(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddComplex64(key string, value complex64) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddComplex64(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddDuration(key string, value time.Duration) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddDuration(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddFloat64(key string, value float64) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddFloat64(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddFloat32(key string, value float32) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddFloat32(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt(key string, value int) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddInt(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt64(key string, value int64) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddInt64(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt32(key string, value int32) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddInt32(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt16(key string, value int16) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddInt16(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt8(key string, value int8) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddInt8(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddTime(key string, value time.Time) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddTime(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint(key string, value uint) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddUint(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint64(key string, value uint64) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddUint64(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint32(key string, value uint32) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddUint32(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint16(key string, value uint16) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddUint16(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint8(key string, value uint8) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddUint8(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddUintptr(key string, value uintptr) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddUintptr(key, value)
+	}
+}
+
+// redactArrayEncoder masks the strings appended to an array by value
+// pattern, and the nested objects by key.
+type redactArrayEncoder struct {
+	zapcore.ArrayEncoder
+	r *redactor
+}
+
+func (e *redactArrayEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
+	return e.ArrayEncoder.AppendArray(redactArray{arr: arr, r: e.r})
+}
+
+func (e *redactArrayEncoder) AppendObject(obj zapcore.ObjectMarshaler) error {
+	return e.ArrayEncoder.AppendObject(redactObject{obj: obj, r: e.r})
+}
+
+func (e *redactArrayEncoder) AppendReflected(value interface{}) error {
+	return e.ArrayEncoder.AppendReflected(e.r.reflected(value))
+}
+
+func (e *redactArrayEncoder) AppendString(value string) {
+	e.ArrayEncoder.AppendString(e.r.redactString(value))
+}
+
+func (e *redactArrayEncoder) AppendByteString(value []byte) {
+	e.ArrayEncoder.AppendByteString([]byte(e.r.redactString(string(value))))
+}
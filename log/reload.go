@@ -0,0 +1,216 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// globalCore is the core of the global logger. Init and Reload swap the core
+// it writes to, so loggers derived from the global one, e.g. with With or
+// Named, follow reloads too.
+var globalCore = newReloadCore(zapcore.NewNopCore(), nil)
+
+// globalRing is the Config.Ring of the global logger, kept by WatchConfig.
+var globalRing atomic.Pointer[Ring]
+
+// reloadState is the core shared by a reloadCore and the loggers derived
+// from it.
+type reloadState struct {
+	// mu is held for reading while an entry is written, so that swap waits
+	// for the writes in progress before closing the previous core
+	mu     sync.RWMutex
+	core   zapcore.Core
+	closer io.Closer
+	// gen counts the swaps, see reloadCore.current
+	gen uint64
+}
+
+// reloadCore writes to a core that can be swapped at runtime. Entries are
+// checked against the current core when they are written, so an entry goes
+// either to the previous core or to the next one, never to both or neither.
+type reloadCore struct {
+	state  *reloadState
+	fields []zapcore.Field
+	// cache holds the current core with fields added
+	cache atomic.Pointer[reloadCached]
+}
+
+type reloadCached struct {
+	gen  uint64
+	core zapcore.Core
+}
+
+func newReloadCore(core zapcore.Core, closer io.Closer) *reloadCore {
+	return &reloadCore{state: &reloadState{core: core, closer: closer}}
+}
+
+// swap makes c write to core, then flushes and closes the previous core once
+// the writes in progress are done.
+func (c *reloadCore) swap(core zapcore.Core, closer io.Closer) error {
+	c.state.mu.Lock()
+	old, oldCloser := c.state.core, c.state.closer
+	c.state.core, c.state.closer = core, closer
+	c.state.gen++
+	c.state.mu.Unlock()
+
+	// syncing stdout fails on some platforms, the closer flushes what matters
+	_ = old.Sync()
+	if oldCloser != nil {
+		return oldCloser.Close()
+	}
+	return nil
+}
+
+// Close flushes and closes the core, entries written after are dropped.
+func (c *reloadCore) Close() error {
+	return c.swap(zapcore.NewNopCore(), nil)
+}
+
+// current returns the current core with the fields of c added. It must be
+// called with state.mu held.
+func (c *reloadCore) current() zapcore.Core {
+	if len(c.fields) == 0 {
+		return c.state.core
+	}
+	if cached := c.cache.Load(); cached != nil && cached.gen == c.state.gen {
+		return cached.core
+	}
+	core := c.state.core.With(c.fields)
+	c.cache.Store(&reloadCached{gen: c.state.gen, core: core})
+	return core
+}
+
+func (c *reloadCore) Enabled(lvl zapcore.Level) bool {
+	c.state.mu.RLock()
+	defer c.state.mu.RUnlock()
+	return c.state.core.Enabled(lvl)
+}
+
+func (c *reloadCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &reloadCore{state: c.state}
+	clone.fields = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	return clone
+}
+
+func (c *reloadCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *reloadCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.state.mu.RLock()
+	defer c.state.mu.RUnlock()
+	if ce := c.current().Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+func (c *reloadCore) Sync() error {
+	c.state.mu.RLock()
+	defer c.state.mu.RUnlock()
+	return c.state.core.Sync()
+}
+
+// Reload rebuilds the outputs of the global logger from config and swaps
+// them in: the level, formats, sinks and rotation all change at once. The
+// previous sinks are flushed and closed after the entries being written to
+// them are done. If config is invalid the global logger is left unchanged.
+func Reload(config *Config) error {
+	core, closer, err := buildCore(config, level)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(toZapLevel(config.Level))
+	// the caller is looked up from before the swap if the new core needs it,
+	// and until after the swap if the previous one does
+	lookup := config.Caller.lookup()
+	if lookup {
+		noCallerLookup.Store(false)
+	}
+	globalRing.Store(config.Ring)
+	if err := globalCore.swap(core, closer); err != nil {
+		// the new outputs are in place, only the previous ones failed
+		fmt.Fprintf(os.Stderr, "%v log: close previous outputs: %v\n", time.Now(), err)
+	}
+	if !lookup {
+		noCallerLookup.Store(true)
+	}
+	return nil
+}
+
+// WatchConfig reloads the global logger from the config file at path, and
+// the environment variables starting with envPrefix, see LoadConfig; the
+// Ring of the global logger, which a file can't set, is kept. The file is
+// read again on SIGHUP and, if interval is positive, every interval; on an
+// interval it is only reloaded when its content changed. Reload errors are
+// logged and leave the logger unchanged. The returned function stops
+// watching.
+func WatchConfig(path, envPrefix string, interval time.Duration) (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	if len(reloadSignals) > 0 {
+		signal.Notify(sigs, reloadSignals...)
+	}
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+
+	last, _ := os.ReadFile(path)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	reload := func(trigger string, force bool) {
+		data, err := os.ReadFile(path)
+		if err == nil && !force && bytes.Equal(data, last) {
+			return
+		}
+		if err == nil {
+			last = data
+			var conf *Config
+			if conf, err = LoadConfig(path, envPrefix); err == nil {
+				conf.Ring = globalRing.Load()
+				err = Reload(conf)
+			}
+		}
+		if err != nil {
+			globalLogger().Errorw("reload log config failed", "path", path, "trigger", trigger, "error", err)
+			return
+		}
+		globalLogger().Infow("log config reloaded", "path", path, "trigger", trigger)
+	}
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case sig := <-sigs:
+				reload(sig.String(), true)
+			case <-tick:
+				reload("interval", false)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		if ticker != nil {
+			ticker.Stop()
+		}
+		close(done)
+		<-stopped
+	}
+}
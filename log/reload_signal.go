@@ -0,0 +1,11 @@
+//go:build !windows
+
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals make WatchConfig reload the config file.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package log
+
+import "os"
+
+// reloadSignals is empty, there is no SIGHUP on windows.
+var reloadSignals []os.Signal
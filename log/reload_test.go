@@ -0,0 +1,174 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReloadWhileLogging(t *testing.T) {
+	sinkA, a := testSink(t)
+	sinkB, b := testSink(t)
+	confA := &Config{Level: "debug", Format: FormatKV, Outputs: []OutputConfig{{Type: sinkA}}}
+	confB := &Config{Level: "debug", Format: FormatJSON, Outputs: []OutputConfig{{Type: sinkB}}, Async: AsyncConfig{Enabled: true, Size: 16}}
+	Init(confA)
+	defer Init(&Config{})
+
+	const writers, entries = 8, 500
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// a derived logger must follow the reloads as well
+			log := logger.With("writer", w)
+			for i := 0; i < entries; i++ {
+				if i%2 == 0 {
+					log.Infof("entry %d-%d", w, i)
+				} else {
+					Infof("entry %d-%d", w, i)
+				}
+			}
+		}(w)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	reloads := 0
+	for running := true; running; reloads++ {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		conf := confA
+		if reloads%2 == 0 {
+			conf = confB
+		}
+		if err := Reload(conf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Sync(); err != nil {
+		t.Fatal(err)
+	}
+	// flush and close the async writer of confB
+	if err := Reload(confA); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]int)
+	for _, m := range regexp.MustCompile(`entry (\d+-\d+)`).FindAllStringSubmatch(a.String()+b.String(), -1) {
+		seen[m[1]]++
+	}
+	for w := 0; w < writers; w++ {
+		for i := 0; i < entries; i++ {
+			id := fmt.Sprintf("%d-%d", w, i)
+			if n := seen[id]; n != 1 {
+				t.Errorf("entry %s written %d times, want once", id, n)
+			}
+		}
+	}
+	if reloads < 2 {
+		t.Errorf("only %d reloads happened while logging", reloads)
+	}
+}
+
+func TestReloadKeepsLoggerOnError(t *testing.T) {
+	sink, a := testSink(t)
+	Init(&Config{Level: "info", Outputs: []OutputConfig{{Type: sink}}})
+	defer Init(&Config{})
+
+	if err := Reload(&Config{Level: "verbose"}); err == nil {
+		t.Fatal("expected an error for an invalid config")
+	}
+	Info("still here")
+	if !bytes.Contains([]byte(a.String()), []byte("still here")) {
+		t.Errorf("the logger changed after a failed reload: %q", a.String())
+	}
+	if GetLevel() != "info" {
+		t.Errorf("level changed to %s after a failed reload", GetLevel())
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.yaml")
+	if err := os.WriteFile(path, []byte("level: info\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sink, _ := testSink(t)
+	Init(&Config{Level: "info", Outputs: []OutputConfig{{Type: sink}}})
+	defer Init(&Config{})
+
+	stop := WatchConfig(path, "", 10*time.Millisecond)
+	defer stop()
+	if err := os.WriteFile(path, []byte("level: debug\noutputs: [{type: "+sink+"}]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for GetLevel() != "debug" {
+		if time.Now().After(deadline) {
+			t.Fatal("the level was not reloaded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWatchConfigKeepsRing(t *testing.T) {
+	sink, _ := testSink(t)
+	path := filepath.Join(t.TempDir(), "log.yaml")
+	if err := os.WriteFile(path, []byte("level: info\noutputs: [{type: "+sink+"}]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ring := NewRing(8)
+	Init(&Config{Level: "info", Outputs: []OutputConfig{{Type: sink}}, Ring: ring})
+	defer Init(&Config{})
+
+	Info("before")
+	stop := WatchConfig(path, "", 10*time.Millisecond)
+	defer stop()
+	if err := os.WriteFile(path, []byte("level: debug\noutputs: [{type: "+sink+"}]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for GetLevel() != "debug" {
+		if time.Now().After(deadline) {
+			t.Fatal("the level was not reloaded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	Debug("after")
+
+	var msgs []string
+	for _, e := range ring.Entries(RingFilter{}) {
+		if e.Message != "log config reloaded" {
+			msgs = append(msgs, e.Message)
+		}
+	}
+	if got := fmt.Sprint(msgs); got != "[before after]" {
+		t.Errorf("ring entries: got %s, want [before after]", got)
+	}
+}
+
+func TestCloseKeepsStdout(t *testing.T) {
+	l, err := New(&Config{Level: "error", Outputs: []OutputConfig{{Type: SinkStdout}, {Type: SinkStderr}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		if _, err := f.Stat(); err != nil {
+			t.Errorf("closing the logger closed %s: %v", f.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultRingSize is the number of entries kept by NewRing(0).
+const DefaultRingSize = 5000
+
+// RingEntry is an entry kept by a Ring, with its fields as they were logged.
+type RingEntry struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Logger  string                 `json:"logger,omitempty"`
+	Caller  string                 `json:"caller,omitempty"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+	Stack   string                 `json:"stack,omitempty"`
+}
+
+// String formats e as a single kv line, the fields sorted by key.
+func (e RingEntry) String() string {
+	var b strings.Builder
+	b.WriteString(e.Time.Format("2006-01-02 15:04:05,000"))
+	b.WriteString(" " + strings.ToUpper(e.Level))
+	if e.Logger != "" {
+		b.WriteString(" " + e.Logger)
+	}
+	if e.Caller != "" {
+		b.WriteString(" " + e.Caller)
+	}
+	b.WriteString(" " + e.Message)
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, e.Fields[k])
+	}
+	if e.Stack != "" {
+		b.WriteString("\n" + e.Stack)
+	}
+	return b.String()
+}
+
+// RingFilter selects entries of a Ring. Zero values match every entry.
+type RingFilter struct {
+	// Level is the lowest level of the entries, e.g. "warn".
+	Level string
+	// Logger matches the logger name and the names under it, as in
+	// Config.Levels.
+	Logger string
+	// Since and Until bound the entry time, inclusive.
+	Since, Until time.Time
+	// Contains is a substring of the message, logger name or a key=value
+	// field, matched case-insensitively.
+	Contains string
+	// Fields are field values the entries must have, compared as formatted
+	// with %v.
+	Fields map[string]string
+	// Limit keeps the latest Limit entries.
+	Limit int
+}
+
+// Validate reports an invalid level.
+func (f *RingFilter) Validate() error {
+	if f.Level == "" {
+		return nil
+	}
+	if _, err := parseLevel(f.Level); err != nil {
+		return fmt.Errorf("log: invalid level %q", f.Level)
+	}
+	return nil
+}
+
+func (f *RingFilter) match(e *ringRecord) bool {
+	if f.Level != "" && e.level < toZapLevel(f.Level) {
+		return false
+	}
+	if f.Logger != "" && e.Logger != f.Logger && !strings.HasPrefix(e.Logger, f.Logger+".") {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	for k, v := range f.Fields {
+		value, ok := e.Fields[k]
+		if !ok || fmt.Sprint(value) != v {
+			return false
+		}
+	}
+	if f.Contains == "" {
+		return true
+	}
+	contains := strings.ToLower(f.Contains)
+	if strings.Contains(strings.ToLower(e.Message), contains) || strings.Contains(strings.ToLower(e.Logger), contains) {
+		return true
+	}
+	for k, v := range e.Fields {
+		if strings.Contains(strings.ToLower(fmt.Sprintf("%s=%v", k, v)), contains) {
+			return true
+		}
+	}
+	return false
+}
+
+type ringRecord struct {
+	RingEntry
+	level zapcore.Level
+}
+
+// Ring keeps the latest entries of a logger in memory, e.g. to serve them
+// over HTTP. Set Config.Ring to add it to a logger; the entries survive
+// reloads as long as the config keeps the same Ring, as WatchConfig does.
+type Ring struct {
+	mu      sync.Mutex
+	records []ringRecord
+	// next is the index the next entry is written at
+	next int
+	full bool
+}
+
+// NewRing returns a ring keeping the latest size entries, DefaultRingSize if
+// size is not positive.
+func NewRing(size int) *Ring {
+	if size <= 0 {
+		size = DefaultRingSize
+	}
+	return &Ring{records: make([]ringRecord, size)}
+}
+
+func (r *Ring) add(rec ringRecord) {
+	r.mu.Lock()
+	r.records[r.next] = rec
+	r.next++
+	if r.next == len(r.records) {
+		r.next = 0
+		r.full = true
+	}
+	r.mu.Unlock()
+}
+
+// Entries returns the entries matching filter, oldest first.
+func (r *Ring) Entries(filter RingFilter) []RingEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var entries []RingEntry
+	// walk from the latest entry back, so that Limit keeps the latest ones
+	for i := 0; i < r.len(); i++ {
+		rec := &r.records[(r.next-1-i+len(r.records))%len(r.records)]
+		if !filter.match(rec) {
+			continue
+		}
+		entries = append(entries, rec.RingEntry)
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries
+}
+
+// Len returns the number of entries in r.
+func (r *Ring) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.len()
+}
+
+func (r *Ring) len() int {
+	if r.full {
+		return len(r.records)
+	}
+	return r.next
+}
+
+// ringCore adds the entries it is given to a Ring.
+type ringCore struct {
+	zapcore.LevelEnabler
+	ring   *Ring
+	fields []zapcore.Field
+}
+
+func newRingCore(ring *Ring, enab zapcore.LevelEnabler) zapcore.Core {
+	return &ringCore{LevelEnabler: enab, ring: ring}
+}
+
+func (c *ringCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &ringCore{LevelEnabler: c.LevelEnabler, ring: c.ring}
+	clone.fields = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	return clone
+}
+
+func (c *ringCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *ringCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var encoded map[string]interface{}
+	if len(c.fields)+len(fields) > 0 {
+		enc := &ringObjectEncoder{zapcore.NewMapObjectEncoder()}
+		for i := range c.fields {
+			c.fields[i].AddTo(enc)
+		}
+		for i := range fields {
+			fields[i].AddTo(enc)
+		}
+		encoded = enc.MapObjectEncoder.Fields
+	}
+	rec := ringRecord{
+		RingEntry: RingEntry{
+			Time:    ent.Time,
+			Level:   ent.Level.String(),
+			Logger:  ent.LoggerName,
+			Message: ent.Message,
+			Fields:  encoded,
+			Stack:   ent.Stack,
+		},
+		level: ent.Level,
+	}
+	if ent.Caller.Defined {
+		rec.Caller = ent.Caller.TrimmedPath()
+	}
+	c.ring.add(rec)
+	return nil
+}
+
+func (c *ringCore) Sync() error {
+	return nil
+}
+
+// ringObjectEncoder encodes the fields of a ring entry. Reflected values are
+// copied as their generic JSON form, so that the ring neither keeps them
+// alive nor reads them while the caller changes them.
+type ringObjectEncoder struct {
+	*zapcore.MapObjectEncoder
+}
+
+func (e *ringObjectEncoder) AddArray(key string, v zapcore.ArrayMarshaler) error {
+	arr := &ringArrayEncoder{&sliceArrayEncoder{}}
+	err := v.MarshalLogArray(arr)
+	e.MapObjectEncoder.AddReflected(key, arr.elems)
+	return err
+}
+
+func (e *ringObjectEncoder) AddObject(key string, v zapcore.ObjectMarshaler) error {
+	obj := &ringObjectEncoder{zapcore.NewMapObjectEncoder()}
+	err := v.MarshalLogObject(obj)
+	e.MapObjectEncoder.AddReflected(key, obj.MapObjectEncoder.Fields)
+	return err
+}
+
+func (e *ringObjectEncoder) AddReflected(key string, v interface{}) error {
+	return e.MapObjectEncoder.AddReflected(key, ringSnapshot(v))
+}
+
+// ringArrayEncoder is the array counterpart of ringObjectEncoder.
+type ringArrayEncoder struct {
+	*sliceArrayEncoder
+}
+
+func (e *ringArrayEncoder) AppendArray(v zapcore.ArrayMarshaler) error {
+	arr := &ringArrayEncoder{&sliceArrayEncoder{}}
+	err := v.MarshalLogArray(arr)
+	e.elems = append(e.elems, arr.elems)
+	return err
+}
+
+func (e *ringArrayEncoder) AppendObject(v zapcore.ObjectMarshaler) error {
+	obj := &ringObjectEncoder{zapcore.NewMapObjectEncoder()}
+	err := v.MarshalLogObject(obj)
+	e.elems = append(e.elems, obj.MapObjectEncoder.Fields)
+	return err
+}
+
+func (e *ringArrayEncoder) AppendReflected(v interface{}) error {
+	e.elems = append(e.elems, ringSnapshot(v))
+	return nil
+}
+
+// ringSnapshot returns a copy of v in its generic JSON form, numbers kept as
+// json.Number, or v formatted with %v if it does not marshal to JSON.
+func ringSnapshot(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return string(b)
+	}
+	return generic
+}
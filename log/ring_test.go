@@ -0,0 +1,87 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func ringMessages(entries []RingEntry) []string {
+	msgs := make([]string, len(entries))
+	for i, e := range entries {
+		msgs[i] = e.Message
+	}
+	return msgs
+}
+
+func TestRing(t *testing.T) {
+	ring := NewRing(4)
+	l, _ := newBufferLogger(t, &Config{Level: "debug", Ring: ring, Redact: RedactConfig{Keys: []string{"password"}}})
+
+	start := time.Now()
+	l.Debug("dropped by the ring size")
+	l.Named("http").Infow("request", "status", 200, "path", "/ping")
+	l.Named("http.client").Warnw("slow request", "status", 200, "took", time.Second)
+	l.Named("db").With("user_id", 42).Errorw("query failed", "password", "hunter2")
+	l.Infow("done")
+
+	if n := ring.Len(); n != 4 {
+		t.Fatalf("ring holds %d entries, want 4", n)
+	}
+	for _, tt := range []struct {
+		filter RingFilter
+		want   string
+	}{
+		{RingFilter{}, "[request slow request query failed done]"},
+		{RingFilter{Level: "warn"}, "[slow request query failed]"},
+		{RingFilter{Logger: "http"}, "[request slow request]"},
+		{RingFilter{Contains: "PATH=/ping"}, "[request]"},
+		{RingFilter{Fields: map[string]string{"status": "200"}}, "[request slow request]"},
+		{RingFilter{Fields: map[string]string{"user_id": "42"}}, "[query failed]"},
+		{RingFilter{Since: start, Until: time.Now()}, "[request slow request query failed done]"},
+		{RingFilter{Until: start.Add(-time.Second)}, "[]"},
+		{RingFilter{Limit: 2}, "[query failed done]"},
+	} {
+		if got := fmt.Sprint(ringMessages(ring.Entries(tt.filter))); got != tt.want {
+			t.Errorf("%+v: got %s, want %s", tt.filter, got, tt.want)
+		}
+	}
+
+	entries := ring.Entries(RingFilter{Logger: "db"})
+	if len(entries) != 1 || entries[0].Fields["password"] != RedactMask {
+		t.Errorf("the ring kept an unredacted entry: %+v", entries)
+	}
+	if entries[0].Caller == "" || entries[0].Level != "error" {
+		t.Errorf("missing caller or level: %+v", entries[0])
+	}
+	if err := (&RingFilter{Level: "loud"}).Validate(); err == nil {
+		t.Error("expected an error for an invalid level")
+	}
+}
+
+func TestRingSnapshotsReflected(t *testing.T) {
+	ring := NewRing(4)
+	l, _ := newBufferLogger(t, &Config{Level: "info", Ring: ring})
+
+	tags := map[string]interface{}{"id": 1<<62 + 1, "tags": []string{"a"}}
+	l.Infow("tagged", "tags", tags, "list", []interface{}{tags})
+	entries := ring.Entries(RingFilter{})
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	// changed while the entry is read, caught by -race if the ring kept tags
+	done := make(chan struct{})
+	go func() {
+		tags["tags"] = []string{"b"}
+		close(done)
+	}()
+	b, err := json.Marshal(entries[0].Fields)
+	<-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"list":[{"id":4611686018427387905,"tags":["a"]}],"tags":{"id":4611686018427387905,"tags":["a"]}}`; string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+}
@@ -0,0 +1,453 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rotation modes of RotateConfig.Mode.
+const (
+	// RotateSize rotates a file when it reaches MaxSize, see lumberjack.
+	RotateSize = "size"
+	// RotateHourly starts a new file every hour.
+	RotateHourly = "hourly"
+	// RotateDaily starts a new file every day, at local midnight.
+	RotateDaily = "daily"
+)
+
+const compressSuffix = ".gz"
+
+// timed reports whether r rotates files by time.
+func (r RotateConfig) timed() bool {
+	return r.Mode == RotateHourly || r.Mode == RotateDaily
+}
+
+// pattern returns the file name pattern of a timed rotation of path.
+func (r RotateConfig) pattern(path string) string {
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(filepath.Base(path), ext)
+	if r.Mode == RotateHourly {
+		return stem + "-%Y-%m-%d-%H" + ext
+	}
+	return stem + "-%Y-%m-%d" + ext
+}
+
+// Tokens of a rotation pattern, with the number of digits they stand for.
+var patternTokens = map[byte]int{
+	'Y': 4, // year
+	'm': 2, // month
+	'd': 2, // day
+	'H': 2, // hour
+}
+
+// rotatePattern formats and parses the names of time rotated files. Names
+// are the pattern with the tokens replaced by the start of the period, then
+// for the files after the first one of a period, when MaxSize caps the
+// files, an index before the extension, e.g. app-2026-10-18.1.log, and
+// ".gz" once compressed.
+type rotatePattern struct {
+	stem string
+	ext  string
+	re   *regexp.Regexp
+	// tokens lists the tokens of stem, in the order of the groups of re
+	tokens []byte
+}
+
+// compileRotatePattern parses pattern, which must hold the tokens telling
+// apart the periods of mode: %Y, %m and %d, plus %H for hourly rotation. %%
+// stands for a literal %.
+func compileRotatePattern(pattern, mode string) (*rotatePattern, error) {
+	if strings.ContainsRune(pattern, filepath.Separator) || strings.Contains(pattern, "/") {
+		return nil, fmt.Errorf("rotate pattern %q must be a file name", pattern)
+	}
+	p := &rotatePattern{ext: filepath.Ext(pattern)}
+	p.stem = strings.TrimSuffix(pattern, p.ext)
+
+	var expr strings.Builder
+	expr.WriteByte('^')
+	seen := make(map[byte]bool)
+	for i := 0; i < len(p.stem); i++ {
+		c := p.stem[i]
+		if c != '%' {
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+			continue
+		}
+		if i++; i == len(p.stem) {
+			return nil, fmt.Errorf("rotate pattern %q ends with %%", pattern)
+		}
+		c = p.stem[i]
+		if c == '%' {
+			expr.WriteByte('%')
+			continue
+		}
+		digits, ok := patternTokens[c]
+		if !ok {
+			return nil, fmt.Errorf("rotate pattern %q: unknown token %%%c", pattern, c)
+		}
+		fmt.Fprintf(&expr, `(\d{%d})`, digits)
+		p.tokens = append(p.tokens, c)
+		seen[c] = true
+	}
+	required := "Ymd"
+	if mode == RotateHourly {
+		required = "YmdH"
+	}
+	for i := 0; i < len(required); i++ {
+		if !seen[required[i]] {
+			return nil, fmt.Errorf("rotate pattern %q needs %%%c for %s rotation", pattern, required[i], mode)
+		}
+	}
+	fmt.Fprintf(&expr, `(?:\.(\d+))?%s(%s)?$`, regexp.QuoteMeta(p.ext), regexp.QuoteMeta(compressSuffix))
+	p.re = regexp.MustCompile(expr.String())
+	return p, nil
+}
+
+// name returns the name of the file of the period starting at t, with index
+// index.
+func (p *rotatePattern) name(t time.Time, index int) string {
+	var b strings.Builder
+	for i := 0; i < len(p.stem); i++ {
+		c := p.stem[i]
+		if c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch p.stem[i] {
+		case 'Y':
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case 'm':
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case '%':
+			b.WriteByte('%')
+		}
+	}
+	if index > 0 {
+		b.WriteString("." + strconv.Itoa(index))
+	}
+	b.WriteString(p.ext)
+	return b.String()
+}
+
+// rotatedFile is a file written by a timeRotator.
+type rotatedFile struct {
+	name       string
+	start      time.Time
+	index      int
+	compressed bool
+}
+
+// parse returns the period start and index of a file named by p.
+func (p *rotatePattern) parse(name string, loc *time.Location) (rotatedFile, bool) {
+	m := p.re.FindStringSubmatch(name)
+	if m == nil {
+		return rotatedFile{}, false
+	}
+	year, month, day, hour := 0, 1, 1, 0
+	for i, tok := range p.tokens {
+		n, _ := strconv.Atoi(m[i+1])
+		switch tok {
+		case 'Y':
+			year = n
+		case 'm':
+			month = n
+		case 'd':
+			day = n
+		case 'H':
+			hour = n
+		}
+	}
+	f := rotatedFile{
+		name:       name,
+		start:      time.Date(year, time.Month(month), day, hour, 0, 0, 0, loc),
+		compressed: m[len(m)-1] != "",
+	}
+	if idx := m[len(m)-2]; idx != "" {
+		f.index, _ = strconv.Atoi(idx)
+	}
+	return f, true
+}
+
+// timeRotator writes to a file per hour or day, named after a pattern. With
+// maxSize set a period can span several files. Finished files are
+// compressed and removed past maxAge or maxBackups in the background.
+type timeRotator struct {
+	dir        string
+	pattern    *rotatePattern
+	hourly     bool
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+	// now returns the current time, replaced in tests
+	now func() time.Time
+
+	mu        sync.Mutex
+	file      *os.File
+	current   rotatedFile
+	size      int64
+	periodEnd time.Time
+
+	// mill runs the compression and removal of finished files, one at a time
+	millMu sync.Mutex
+	millWg sync.WaitGroup
+}
+
+func newTimeRotator(path string, conf RotateConfig) (*timeRotator, error) {
+	pattern, err := compileRotatePattern(conf.pattern(path), conf.Mode)
+	if err != nil {
+		return nil, err
+	}
+	// a limit left at 0 is off, unless both are
+	maxAge, maxBackups := conf.MaxAge, conf.MaxBackups
+	if maxAge <= 0 && maxBackups <= 0 {
+		maxAge, maxBackups = RotateMaxAge, RotateMaxBackups
+	}
+	return &timeRotator{
+		dir:        filepath.Dir(path),
+		pattern:    pattern,
+		hourly:     conf.Mode == RotateHourly,
+		maxSize:    int64(conf.MaxSize) * 1024 * 1024,
+		maxAge:     time.Duration(maxAge) * 24 * time.Hour,
+		maxBackups: maxBackups,
+		compress:   conf.Compress,
+		now:        time.Now,
+	}, nil
+}
+
+// periodStart returns the start of the period t falls in.
+func (r *timeRotator) periodStart(t time.Time) time.Time {
+	hour := 0
+	if r.hourly {
+		hour = t.Hour()
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), hour, 0, 0, 0, t.Location())
+}
+
+func (r *timeRotator) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	switch {
+	case r.file == nil || !now.Before(r.periodEnd):
+		if err := r.openPeriod(now); err != nil {
+			return 0, err
+		}
+	case r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize:
+		if err := r.openFile(rotatedFile{start: r.current.start, index: r.current.index + 1}); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// openPeriod opens the last file of the period now falls in, appending to
+// it if the process wrote to it before.
+func (r *timeRotator) openPeriod(now time.Time) error {
+	start := r.periodStart(now)
+	if r.hourly {
+		r.periodEnd = r.periodStart(start.Add(90 * time.Minute))
+	} else {
+		r.periodEnd = time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, start.Location())
+	}
+	next := rotatedFile{start: start}
+	files, _ := r.files(now.Location())
+	for _, f := range files {
+		if f.start.Equal(start) && f.index >= next.index {
+			next.index = f.index
+			if f.compressed {
+				next.index++
+			}
+		}
+	}
+	return r.openFile(next)
+}
+
+// openFile closes the current file and opens f for appending.
+func (r *timeRotator) openFile(f rotatedFile) error {
+	if err := r.closeFile(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return err
+	}
+	f.name = r.pattern.name(f.start, f.index)
+	file, err := os.OpenFile(filepath.Join(r.dir, f.name), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.current, r.size = file, f, info.Size()
+	r.startMill()
+	return nil
+}
+
+func (r *timeRotator) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *timeRotator) Sync() error {
+	return nil
+}
+
+// Close closes the current file and waits for the compression and removal
+// of finished files.
+func (r *timeRotator) Close() error {
+	r.mu.Lock()
+	err := r.closeFile()
+	r.mu.Unlock()
+	r.millWg.Wait()
+	return err
+}
+
+// files lists the files of the pattern, newest first.
+func (r *timeRotator) files(loc *time.Location) ([]rotatedFile, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []rotatedFile
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if f, ok := r.pattern.parse(e.Name(), loc); ok {
+			files = append(files, f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].start.Equal(files[j].start) {
+			return files[i].start.After(files[j].start)
+		}
+		return files[i].index > files[j].index
+	})
+	return files, nil
+}
+
+// startMill compresses and removes the finished files in the background.
+// It must be called with mu held.
+func (r *timeRotator) startMill() {
+	now := r.now()
+	r.millWg.Add(1)
+	go func() {
+		defer r.millWg.Done()
+		r.millMu.Lock()
+		defer r.millMu.Unlock()
+		if err := r.mill(now); err != nil {
+			fmt.Fprintf(os.Stderr, "%v log: rotate %s: %v\n", time.Now(), r.dir, err)
+		}
+	}()
+}
+
+// mill removes the finished files older than maxAge and beyond maxBackups,
+// then compresses the others if compress is set. Finished files are the
+// ones before the current file; files opened since the mill started are
+// newer, so they are left alone.
+func (r *timeRotator) mill(now time.Time) error {
+	r.mu.Lock()
+	current := r.current
+	files, err := r.files(now.Location())
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var cutoff time.Time
+	if r.maxAge > 0 {
+		cutoff = now.Add(-r.maxAge)
+	}
+	var errs []error
+	backups := 0
+	for _, f := range files {
+		if !f.before(current) {
+			continue
+		}
+		backups++
+		path := filepath.Join(r.dir, f.name)
+		if (r.maxBackups > 0 && backups > r.maxBackups) || f.start.Before(cutoff) {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if r.compress && !f.compressed {
+			if err := compressFile(path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// before reports whether f was written before g.
+func (f rotatedFile) before(g rotatedFile) bool {
+	if !f.start.Equal(g.start) {
+		return f.start.Before(g.start)
+	}
+	return f.index < g.index
+}
+
+// compressFile gzips path to path.gz and removes path.
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(path + compressSuffix)
+		}
+	}()
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,195 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fakeClock is the clock of a timeRotator under test.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestRotator(t *testing.T, dir string, conf RotateConfig, clock *fakeClock) *timeRotator {
+	t.Helper()
+	r, err := newTimeRotator(filepath.Join(dir, "app.log"), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.now = clock.now
+	return r
+}
+
+func rotatorWrite(t *testing.T, r *timeRotator, s string) {
+	t.Helper()
+	if _, err := r.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func dirFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = f
+		if filepath.Ext(path) == compressSuffix {
+			if r, err = gzip.NewReader(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data, err := io.ReadAll(r)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[e.Name()] = string(data)
+	}
+	return files
+}
+
+func sortedNames(files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestTimeRotatorDaily(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2026, 10, 18, 23, 59, 0, 0, time.Local)}
+	r := newTestRotator(t, dir, RotateConfig{Mode: RotateDaily, Compress: true}, clock)
+
+	rotatorWrite(t, r, "a\n")
+	clock.t = clock.t.Add(2 * time.Minute)
+	rotatorWrite(t, r, "b\n")
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := dirFiles(t, dir)
+	want := map[string]string{"app-2026-10-18.log.gz": "a\n", "app-2026-10-19.log": "b\n"}
+	if len(got) != len(want) {
+		t.Fatalf("got files %v, want %v", sortedNames(got), sortedNames(want))
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Errorf("%s: got %q, want %q", name, got[name], content)
+		}
+	}
+}
+
+func TestTimeRotatorHourlySizeCap(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2026, 10, 18, 8, 10, 0, 0, time.Local)}
+	conf := RotateConfig{Mode: RotateHourly, Pattern: "svc.%Y%m%d%H.log"}
+	r := newTestRotator(t, dir, conf, clock)
+	r.maxSize = 10
+
+	for _, s := range []string{"first\n", "second\n", "third\n"} {
+		rotatorWrite(t, r, s)
+	}
+	clock.t = clock.t.Add(time.Hour)
+	rotatorWrite(t, r, "next hour\n")
+	r.Close()
+
+	// a restarted process appends to the last file of the period
+	r = newTestRotator(t, dir, conf, clock)
+	rotatorWrite(t, r, "again\n")
+	r.Close()
+
+	got := dirFiles(t, dir)
+	want := map[string]string{
+		"svc.2026101808.log":   "first\n",
+		"svc.2026101808.1.log": "second\n",
+		"svc.2026101808.2.log": "third\n",
+		"svc.2026101809.log":   "next hour\nagain\n",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got files %v, want %v", sortedNames(got), sortedNames(want))
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Errorf("%s: got %q, want %q", name, got[name], content)
+		}
+	}
+}
+
+func TestTimeRotatorRetention(t *testing.T) {
+	dir := t.TempDir()
+	// an old file past MaxAge and a file of another pattern
+	for _, name := range []string{"app-2026-09-01.log.gz", "other.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock := &fakeClock{t: time.Date(2026, 10, 10, 12, 0, 0, 0, time.Local)}
+	r := newTestRotator(t, dir, RotateConfig{Mode: RotateDaily, MaxAge: 30, MaxBackups: 3}, clock)
+	for i := 0; i < 6; i++ {
+		rotatorWrite(t, r, "x")
+		clock.t = clock.t.AddDate(0, 0, 1)
+	}
+	r.Close()
+
+	got := sortedNames(dirFiles(t, dir))
+	want := []string{"app-2026-10-12.log", "app-2026-10-13.log", "app-2026-10-14.log", "app-2026-10-15.log", "other.log"}
+	if len(got) != len(want) {
+		t.Fatalf("got files %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got files %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRotatePatternErrors(t *testing.T) {
+	for _, tt := range []struct{ pattern, mode string }{
+		{"app-%Y-%m.log", RotateDaily},
+		{"app-%Y-%m-%d.log", RotateHourly},
+		{"app-%Y-%m-%d-%q.log", RotateDaily},
+		{"logs/app-%Y-%m-%d.log", RotateDaily},
+	} {
+		if _, err := compileRotatePattern(tt.pattern, tt.mode); err == nil {
+			t.Errorf("%s rotation with pattern %q: expected an error", tt.mode, tt.pattern)
+		}
+	}
+	conf := Config{Outputs: []OutputConfig{{Type: SinkFile, Path: filepath.Join(t.TempDir(), "app.log"), Rotate: RotateConfig{Mode: "weekly"}}}}
+	if err := conf.Validate(); err == nil {
+		t.Error("expected an error for an unknown rotate mode")
+	}
+}
+
+func TestTimeRotatorRetentionByAge(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2026, 7, 1, 12, 0, 0, 0, time.Local)}
+	r := newTestRotator(t, dir, RotateConfig{Mode: RotateDaily, MaxAge: 20}, clock)
+	for i := 0; i < 30; i++ {
+		rotatorWrite(t, r, "x")
+		clock.t = clock.t.AddDate(0, 0, 1)
+	}
+	r.Close()
+
+	// no count limit: the files started within the last 20 days
+	got := sortedNames(dirFiles(t, dir))
+	if len(got) != 20 || got[0] != "app-2026-07-11.log" || got[19] != "app-2026-07-30.log" {
+		t.Errorf("got files %v, want 2026-07-11 to 2026-07-30", got)
+	}
+}
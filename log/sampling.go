@@ -0,0 +1,233 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingConfig configures zap style sampling: within every Tick, the first
+// Initial entries with a given level and message are logged, then every
+// Thereafter-th one. Sampling is off while Initial is 0.
+type SamplingConfig struct {
+	Tick       time.Duration `yaml:"tick" json:"tick" mapstructure:"tick"`
+	Initial    int           `yaml:"initial" json:"initial" mapstructure:"initial"`
+	Thereafter int           `yaml:"thereafter" json:"thereafter" mapstructure:"thereafter"`
+}
+
+// RateLimitConfig limits each message to Burst entries per Window, keyed by
+// level, logger name and message. When a window with suppressed entries is
+// over, or the logger is synced or closed, a summary entry "suppressed N
+// similar messages" is logged. Rate limiting is off while Burst is 0.
+type RateLimitConfig struct {
+	Window time.Duration `yaml:"window" json:"window" mapstructure:"window"`
+	Burst  int           `yaml:"burst" json:"burst" mapstructure:"burst"`
+}
+
+func newSamplerCore(core zapcore.Core, conf SamplingConfig) zapcore.Core {
+	if conf.Initial <= 0 {
+		return core
+	}
+	tick := conf.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+	return zapcore.NewSamplerWithOptions(core, tick, conf.Initial, conf.Thereafter)
+}
+
+type rateLimitKey struct {
+	level   zapcore.Level
+	logger  string
+	message string
+}
+
+type rateLimitWindow struct {
+	start      time.Time
+	count      int
+	suppressed int
+	// core is used to write the summary, it carries the fields of the
+	// logger that opened the window
+	core zapcore.Core
+}
+
+type rateLimiter struct {
+	window time.Duration
+	burst  int
+	now    func() time.Time
+
+	mu        sync.Mutex
+	lastSweep time.Time
+	windows   map[rateLimitKey]*rateLimitWindow
+	// timer is set while it is due to write the summaries of the next
+	// window to close
+	timer *time.Timer
+}
+
+type rateLimitSummary struct {
+	core       zapcore.Core
+	key        rateLimitKey
+	suppressed int
+}
+
+// rateLimitCore drops entries over the burst of their key, see
+// RateLimitConfig. The summary of a window is written by a timer when the
+// window is over, or earlier on Sync, which stops the timer: the sinks may
+// be closed after a Sync, e.g. by Reload.
+type rateLimitCore struct {
+	zapcore.Core
+	limiter *rateLimiter
+}
+
+func newRateLimitCore(core zapcore.Core, conf RateLimitConfig) zapcore.Core {
+	if conf.Burst <= 0 {
+		return core
+	}
+	window := conf.Window
+	if window <= 0 {
+		window = time.Second
+	}
+	return &rateLimitCore{
+		Core: core,
+		limiter: &rateLimiter{
+			window:  window,
+			burst:   conf.Burst,
+			now:     time.Now,
+			windows: make(map[rateLimitKey]*rateLimitWindow),
+		},
+	}
+}
+
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rateLimitCore{Core: c.Core.With(fields), limiter: c.limiter}
+}
+
+func (c *rateLimitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// never hold back entries that panic or exit
+		return c.Core.Check(ent, ce)
+	}
+	key := rateLimitKey{level: ent.Level, logger: ent.LoggerName, message: ent.Message}
+	allow, summaries := c.limiter.allow(key, c.Core)
+	writeSummaries(summaries)
+	if !allow {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c *rateLimitCore) Sync() error {
+	writeSummaries(c.limiter.flush())
+	return c.Core.Sync()
+}
+
+// allow counts an entry of key and reports whether it is within the burst,
+// along with the summaries of the windows closed since the last sweep.
+func (l *rateLimiter) allow(key rateLimitKey, core zapcore.Core) (bool, []rateLimitSummary) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var summaries []rateLimitSummary
+	if now.Sub(l.lastSweep) >= l.window {
+		summaries = l.sweep(now, false)
+		l.lastSweep = now
+	}
+
+	w, ok := l.windows[key]
+	if ok && now.Sub(w.start) >= l.window {
+		if w.suppressed > 0 {
+			summaries = append(summaries, rateLimitSummary{core: w.core, key: key, suppressed: w.suppressed})
+		}
+		ok = false
+	}
+	if !ok {
+		w = &rateLimitWindow{start: now, core: core}
+		l.windows[key] = w
+	}
+	w.count++
+	if w.count > l.burst {
+		w.suppressed++
+		if l.timer == nil {
+			l.expireAfter(w.start.Add(l.window).Sub(now))
+		}
+		return false, summaries
+	}
+	return true, summaries
+}
+
+// expireAfter writes the summaries of the windows that are over after d.
+// It must be called with l.mu held. The summaries are written with l.mu
+// held too, so that flush waits for them, and a timer stopped by flush
+// while waiting for l.mu writes nothing.
+func (l *rateLimiter) expireAfter(d time.Duration) {
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.timer != timer {
+			return
+		}
+		l.timer = nil
+		now := l.now()
+		summaries := l.sweep(now, false)
+		l.lastSweep = now
+		var next time.Time
+		for _, w := range l.windows {
+			if w.suppressed > 0 && (next.IsZero() || w.start.Before(next)) {
+				next = w.start
+			}
+		}
+		if !next.IsZero() {
+			l.expireAfter(next.Add(l.window).Sub(now))
+		}
+		writeSummaries(summaries)
+	})
+	l.timer = timer
+}
+
+// flush stops the pending timer and returns the summaries of all windows.
+func (l *rateLimiter) flush() []rateLimitSummary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	return l.sweep(l.now(), true)
+}
+
+// sweep drops the windows that are over, or all of them if all is set, and
+// returns the summaries of the ones with suppressed entries.
+func (l *rateLimiter) sweep(now time.Time, all bool) []rateLimitSummary {
+	var summaries []rateLimitSummary
+	for key, w := range l.windows {
+		if !all && now.Sub(w.start) < l.window {
+			continue
+		}
+		if w.suppressed > 0 {
+			summaries = append(summaries, rateLimitSummary{core: w.core, key: key, suppressed: w.suppressed})
+		}
+		delete(l.windows, key)
+	}
+	return summaries
+}
+
+func writeSummaries(summaries []rateLimitSummary) {
+	for _, s := range summaries {
+		ent := zapcore.Entry{
+			Level:      s.key.level,
+			LoggerName: s.key.logger,
+			Time:       time.Now(),
+			Message:    fmt.Sprintf("suppressed %d similar messages: %s", s.suppressed, s.key.message),
+		}
+		if ce := s.core.Check(ent, nil); ce != nil {
+			ce.Write(zap.Int("suppressed", s.suppressed))
+		}
+	}
+}
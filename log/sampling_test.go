@@ -0,0 +1,127 @@
+package log
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRateLimitCore(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	core := newRateLimitCore(obs, RateLimitConfig{Window: time.Second, Burst: 2}).(*rateLimitCore)
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	core.limiter.now = func() time.Time { return now }
+	l := zap.New(core)
+
+	for i := 0; i < 5; i++ {
+		l.Warn("disk almost full")
+	}
+	l.Info("other message")
+	if got := logs.FilterMessage("disk almost full").Len(); got != 2 {
+		t.Fatalf("got %d entries within the window, want 2", got)
+	}
+
+	now = now.Add(time.Second)
+	l.Warn("disk almost full")
+
+	summary := logs.FilterMessage("suppressed 3 similar messages: disk almost full").All()
+	if len(summary) != 1 {
+		t.Fatalf("got %d summaries, want 1: %v", len(summary), logs.All())
+	}
+	if summary[0].Level != zapcore.WarnLevel || summary[0].ContextMap()["suppressed"] != int64(3) {
+		t.Errorf("unexpected summary %+v", summary[0])
+	}
+	if got := logs.FilterMessage("disk almost full").Len(); got != 3 {
+		t.Errorf("got %d entries after the window, want 3", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		l.Warn("disk almost full")
+	}
+	l.Sync()
+	if got := logs.FilterMessage("suppressed 2 similar messages: disk almost full").Len(); got != 1 {
+		t.Errorf("got %d summaries on Sync, want 1", got)
+	}
+}
+
+func TestRateLimitSummaryOnExpiry(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(newRateLimitCore(obs, RateLimitConfig{Window: 20 * time.Millisecond, Burst: 1}))
+	for i := 0; i < 3; i++ {
+		l.Warn("disk almost full")
+	}
+
+	// no later entry nor Sync: the window timer writes the summary
+	deadline := time.Now().Add(5 * time.Second)
+	for logs.FilterMessage("suppressed 2 similar messages: disk almost full").Len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no summary after the window: %v", logs.All())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRateLimitSyncStopsTimer(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	core := newRateLimitCore(obs, RateLimitConfig{Window: 20 * time.Millisecond, Burst: 1}).(*rateLimitCore)
+	l := zap.New(core)
+	for i := 0; i < 3; i++ {
+		l.Warn("disk almost full")
+	}
+	// e.g. Reload syncs the core before closing its sinks
+	l.Sync()
+	core.limiter.mu.Lock()
+	timer := core.limiter.timer
+	core.limiter.mu.Unlock()
+	if timer != nil {
+		t.Error("the summary timer is still pending after Sync")
+	}
+	n := logs.Len()
+	time.Sleep(50 * time.Millisecond)
+	if logs.Len() != n {
+		t.Errorf("wrote %v after Sync", logs.All()[n:])
+	}
+}
+
+// testClock is a zapcore.Clock set by the tests.
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time                         { return c.now }
+func (c *testClock) NewTicker(d time.Duration) *time.Ticker { return time.NewTicker(d) }
+
+func TestSamplerCore(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	if core := newSamplerCore(obs, SamplingConfig{}); core != zapcore.Core(obs) {
+		t.Error("sampling is on without Initial")
+	}
+
+	clock := &testClock{now: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)}
+	l := zap.New(newSamplerCore(obs, SamplingConfig{Tick: time.Second, Initial: 2, Thereafter: 3}), zap.WithClock(clock))
+	sampled := func() []int64 {
+		var n []int64
+		for _, e := range logs.FilterMessage("cache miss").FilterLevelExact(zapcore.InfoLevel).All() {
+			n = append(n, e.ContextMap()["n"].(int64))
+		}
+		return n
+	}
+	for i := 1; i <= 10; i++ {
+		l.Info("cache miss", zap.Int("n", i))
+	}
+	l.Warn("cache miss")
+	if got := fmt.Sprint(sampled()); got != "[1 2 5 8]" {
+		t.Errorf("sampled %s, want the first 2 then every 3rd", got)
+	}
+	if got := logs.FilterLevelExact(zapcore.WarnLevel).Len(); got != 1 {
+		t.Errorf("got %d warn entries, levels are sampled apart", got)
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	l.Info("cache miss", zap.Int("n", 11))
+	if got := fmt.Sprint(sampled()); got != "[1 2 5 8 11]" {
+		t.Errorf("sampled %s after a tick, want 11 kept", got)
+	}
+}
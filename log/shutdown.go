@@ -0,0 +1,111 @@
+package log
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	shutdownMu    sync.Mutex
+	shutdownHooks []func()
+	// shutdownWatching is set while a goroutine waits for SIGTERM
+	shutdownWatching bool
+	// shutdownStay keeps the process running after SIGTERM, see
+	// SetExitOnShutdown
+	shutdownStay atomic.Bool
+
+	// exit ends the process, replaced in tests
+	exit = os.Exit
+)
+
+// Close flushes and closes the outputs of the global logger, async and
+// remote ones included. Entries logged after Close are dropped, until Init
+// or Reload sets up new outputs.
+func Close() error {
+	return globalCore.Close()
+}
+
+// OnShutdown registers fn to run when the process shuts down, see Shutdown,
+// and makes the package handle SIGTERM: on the signal Shutdown runs, then the
+// process exits with status 143. A second SIGTERM stops the process at once.
+//
+// An application shutting down on SIGTERM itself, e.g. with
+// http.Server.Shutdown, would be cut short by that exit: it must call
+// SetExitOnShutdown(false), and then call Close or Shutdown itself once done.
+func OnShutdown(fn func()) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	shutdownHooks = append(shutdownHooks, fn)
+	if !shutdownWatching {
+		shutdownWatching = true
+		watchShutdownSignals()
+	}
+}
+
+// SetExitOnShutdown sets whether the process exits on SIGTERM once OnShutdown
+// was called, the default. Turned off, the hooks still run on SIGTERM, but
+// the global logger is left open and the process keeps running: the
+// application must exit itself, calling Close or Shutdown last.
+func SetExitOnShutdown(exit bool) {
+	shutdownStay.Store(!exit)
+}
+
+// Shutdown runs the hooks registered with OnShutdown, the latest first, then
+// closes the global logger with Close. The hooks run once, later calls only
+// close the logger.
+func Shutdown() error {
+	runShutdownHooks()
+	return Close()
+}
+
+// runShutdownHooks runs the hooks not run yet, the latest first.
+func runShutdownHooks() {
+	shutdownMu.Lock()
+	hooks := shutdownHooks
+	shutdownHooks = nil
+	shutdownMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+func watchShutdownSignals() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTERM)
+	go func() {
+		sig := <-ch
+		// the next SIGTERM stops the process as usual, until OnShutdown is
+		// called again
+		signal.Stop(ch)
+		shutdownMu.Lock()
+		shutdownWatching = false
+		shutdownMu.Unlock()
+		globalLogger().Infow("shutting down", "signal", sig.String())
+		if shutdownStay.Load() {
+			runShutdownHooks()
+			return
+		}
+		Shutdown()
+		exit(128 + int(syscall.SIGTERM))
+	}()
+}
+
+// fatalHook closes the sinks of a logger, flushing the entries queued by
+// async and remote sinks, before exiting after a Fatal entry.
+type fatalHook struct {
+	closer io.Closer
+}
+
+func (h fatalHook) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {
+	if h.closer != nil {
+		h.closer.Close()
+	}
+	exit(1)
+}
@@ -0,0 +1,69 @@
+//go:build !windows
+
+package log
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// sigterm sends SIGTERM to the test process once OnShutdown registered a
+// hook, and waits for the hook.
+func sigterm(t *testing.T) {
+	t.Helper()
+	done := make(chan struct{})
+	OnShutdown(func() { close(done) })
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hook did not run on SIGTERM")
+	}
+}
+
+func TestShutdownOnSIGTERM(t *testing.T) {
+	exited := make(chan int, 1)
+	exit = func(code int) { exited <- code }
+	defer func() { exit = osExit }()
+	sink, buf := testSink(t)
+	Init(&Config{Level: "info", Outputs: []OutputConfig{{Type: sink}}})
+	defer Init(&Config{})
+
+	sigterm(t)
+	select {
+	case code := <-exited:
+		if code != 143 {
+			t.Errorf("exited with %d, want 143", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the process did not exit on SIGTERM")
+	}
+	if !strings.Contains(buf.String(), "signal=terminated") {
+		t.Errorf("no shutdown entry in %q", buf.String())
+	}
+	Info("after close")
+	if strings.Contains(buf.String(), "after close") {
+		t.Error("the logger is open after exiting")
+	}
+
+	// an application with a shutdown of its own keeps running, and logging
+	Init(&Config{Level: "info", Outputs: []OutputConfig{{Type: sink}}})
+	SetExitOnShutdown(false)
+	defer SetExitOnShutdown(true)
+	sigterm(t)
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case code := <-exited:
+		t.Errorf("exited with %d, exiting is left to the application", code)
+	default:
+	}
+	Info("own shutdown")
+	if !strings.Contains(buf.String(), "own shutdown") {
+		t.Errorf("the logger was closed: %q", buf.String())
+	}
+}
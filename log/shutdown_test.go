@@ -0,0 +1,86 @@
+package log
+
+import (
+	"strings"
+	"testing"
+)
+
+// stubExit replaces exit for the test and records the status codes.
+func stubExit(t *testing.T) *[]int {
+	t.Helper()
+	var codes []int
+	exit = func(code int) { codes = append(codes, code) }
+	t.Cleanup(func() { exit = osExit })
+	return &codes
+}
+
+var osExit = exit
+
+func TestFatalFlushesAsyncSink(t *testing.T) {
+	codes := stubExit(t)
+	sink, buf := testSink(t)
+	l, err := New(&Config{Level: "info", Outputs: []OutputConfig{{Type: sink}}, Async: AsyncConfig{Enabled: true, Size: 16}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Info("before")
+	l.Fatal("fatal error")
+	if len(*codes) != 1 || (*codes)[0] != 1 {
+		t.Fatalf("exit codes %v, want [1]", *codes)
+	}
+	for _, msg := range []string{"before", "fatal error"} {
+		if !strings.Contains(buf.String(), msg) {
+			t.Errorf("%q was not flushed before exiting: %q", msg, buf.String())
+		}
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	sink, buf := testSink(t)
+	Init(&Config{Level: "info", Outputs: []OutputConfig{{Type: sink}}, Async: AsyncConfig{Enabled: true, Size: 16}})
+	defer Init(&Config{})
+
+	var order []int
+	OnShutdown(func() { order = append(order, 1) })
+	OnShutdown(func() {
+		order = append(order, 2)
+		Info("last entry")
+	})
+	if err := Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 2 || order[0] != 2 || order[1] != 1 {
+		t.Errorf("hooks ran in order %v, want [2 1]", order)
+	}
+	if !strings.Contains(buf.String(), "last entry") {
+		t.Errorf("the entry of a hook was not flushed: %q", buf.String())
+	}
+
+	// the outputs are closed, the hooks do not run again
+	Info("dropped")
+	if err := Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 2 {
+		t.Errorf("hooks ran again: %v", order)
+	}
+	if strings.Contains(buf.String(), "dropped") {
+		t.Errorf("an entry was written after Close: %q", buf.String())
+	}
+}
+
+func TestLoggerClose(t *testing.T) {
+	sink, buf := testSink(t)
+	l, err := New(&Config{Level: "info", Outputs: []OutputConfig{{Type: sink}}, Async: AsyncConfig{Enabled: true, Size: 16}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("queued")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "queued") {
+		t.Errorf("Close did not flush the async sink: %q", buf.String())
+	}
+}
@@ -0,0 +1,114 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mattn/go-isatty"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Built-in sink types of OutputConfig.Type.
+const (
+	SinkStdout = "stdout"
+	SinkStderr = "stderr"
+	SinkFile   = "file"
+	SinkSyslog = "syslog"
+	SinkTCP    = "tcp"
+	SinkUDP    = "udp"
+)
+
+// SinkFactory creates the WriteSyncer of an output.
+type SinkFactory func(output OutputConfig) (zapcore.WriteSyncer, error)
+
+var (
+	sinkMu    sync.RWMutex
+	sinkTypes = map[string]SinkFactory{}
+)
+
+func init() {
+	RegisterSink(SinkStdout, func(OutputConfig) (zapcore.WriteSyncer, error) {
+		return zapcore.AddSync(os.Stdout), nil
+	})
+	RegisterSink(SinkStderr, func(OutputConfig) (zapcore.WriteSyncer, error) {
+		return zapcore.AddSync(os.Stderr), nil
+	})
+	RegisterSink(SinkFile, newFileSink)
+	RegisterSink(SinkSyslog, newSyslogSink)
+	RegisterSink(SinkTCP, newNetSink)
+	RegisterSink(SinkUDP, newNetSink)
+}
+
+// RegisterSink makes a sink type available to OutputConfig.Type. Registering
+// an existing type replaces it.
+func RegisterSink(typ string, factory SinkFactory) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	sinkTypes[typ] = factory
+}
+
+func newSink(output OutputConfig) (zapcore.WriteSyncer, error) {
+	typ := output.Type
+	if typ == "" {
+		typ = SinkStdout
+	}
+	sinkMu.RLock()
+	factory, ok := sinkTypes[typ]
+	sinkMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("log: unknown sink type %q", typ)
+	}
+	ws, err := factory(output)
+	if err != nil {
+		return nil, fmt.Errorf("log: create %s sink: %w", typ, err)
+	}
+	return ws, nil
+}
+
+// isTerminal reports whether the sink type writes to a terminal, where
+// levels are colored.
+func isTerminal(typ string) bool {
+	var f *os.File
+	switch typ {
+	case "", SinkStdout:
+		f = os.Stdout
+	case SinkStderr:
+		f = os.Stderr
+	default:
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+func newFileSink(output OutputConfig) (zapcore.WriteSyncer, error) {
+	if output.Path == "" {
+		return nil, fmt.Errorf("file path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(output.Path), 0755); err != nil {
+		return nil, err
+	}
+	if output.Rotate.timed() {
+		return newTimeRotator(output.Path, output.Rotate)
+	}
+	// opened the way lumberjack does, so the logger fails to build rather
+	// than to write
+	f, err := os.OpenFile(output.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return fileSink{newRotateHook(output.Path, output.Rotate)}, nil
+}
+
+// fileSink writes to a rotating file.
+type fileSink struct {
+	*lumberjack.Logger
+}
+
+// Sync is a no-op, lumberjack doesn't buffer writes.
+func (fileSink) Sync() error {
+	return nil
+}
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// levelWriter is implemented by sinks that need the level of every entry,
+// e.g. to set the syslog severity.
+type levelWriter interface {
+	WriteLevel(lvl zapcore.Level, p []byte) (int, error)
+}
+
+// sinkCore is zapcore's ioCore, except that it hands the entry level to sinks
+// implementing levelWriter.
+type sinkCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	out zapcore.WriteSyncer
+}
+
+func newSinkCore(enc zapcore.Encoder, out zapcore.WriteSyncer, enab zapcore.LevelEnabler) zapcore.Core {
+	return &sinkCore{LevelEnabler: enab, enc: enc, out: out}
+}
+
+func (c *sinkCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &sinkCore{LevelEnabler: c.LevelEnabler, enc: c.enc.Clone(), out: c.out}
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *sinkCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *sinkCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	_, err = writeLevel(c.out, ent.Level, buf.Bytes())
+	buf.Free()
+	if err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// Since we may be crashing the program, sync the output.
+		c.Sync() //nolint:errcheck
+	}
+	return nil
+}
+
+func (c *sinkCore) Sync() error {
+	return c.out.Sync()
+}
@@ -0,0 +1,98 @@
+package log
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	netSinkDialTimeout = 3 * time.Second
+	netSinkMinBackoff  = 100 * time.Millisecond
+	netSinkMaxBackoff  = 30 * time.Second
+)
+
+// netSinkWriteTimeout bounds a write to the connection, replaced in tests.
+var netSinkWriteTimeout = time.Second
+
+// netSink writes one entry per line to a tcp or udp endpoint. The connection
+// is dialed on the first write and re-dialed after a write error, so a log
+// collector that is down does not keep the service from starting. After a
+// failed dial or write the sink backs off, doubling the wait up to
+// netSinkMaxBackoff, and the writes meanwhile fail at once instead of
+// dialing again.
+type netSink struct {
+	mu      sync.Mutex
+	network string
+	address string
+	conn    net.Conn
+	backoff time.Duration
+	retryAt time.Time
+}
+
+func newNetSink(output OutputConfig) (zapcore.WriteSyncer, error) {
+	network := output.Type
+	if output.Address == "" {
+		return nil, fmt.Errorf("address is empty")
+	}
+	if _, _, err := net.SplitHostPort(output.Address); err != nil {
+		return nil, err
+	}
+	return &netSink{network: network, address: output.Address}, nil
+}
+
+func (s *netSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		if wait := time.Until(s.retryAt); wait > 0 {
+			return 0, fmt.Errorf("%s %s is down, retrying in %v", s.network, s.address, wait.Round(time.Millisecond))
+		}
+		conn, err := net.DialTimeout(s.network, s.address, netSinkDialTimeout)
+		if err != nil {
+			s.backOff()
+			return 0, err
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(netSinkWriteTimeout))
+	n, err := s.conn.Write(p)
+	if err != nil {
+		s.conn.Close()
+		s.conn = nil
+		s.backOff()
+		return n, err
+	}
+	s.backoff = 0
+	return n, nil
+}
+
+// backOff delays the next dial, twice as long as the previous one.
+func (s *netSink) backOff() {
+	s.backoff *= 2
+	if s.backoff < netSinkMinBackoff {
+		s.backoff = netSinkMinBackoff
+	}
+	if s.backoff > netSinkMaxBackoff {
+		s.backoff = netSinkMaxBackoff
+	}
+	s.retryAt = time.Now().Add(s.backoff)
+}
+
+func (s *netSink) Sync() error {
+	return nil
+}
+
+func (s *netSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,89 @@
+package log
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNetSinkCollectorDown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	ws, err := newNetSink(OutputConfig{Type: SinkTCP, Address: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := ws.(*netSink)
+	defer s.Close()
+	if _, err := s.Write([]byte("refused\n")); err == nil {
+		t.Fatal("wrote to a closed port")
+	}
+
+	// the collector is back, but the sink waits for the backoff
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	if _, err := s.Write([]byte("backing off\n")); err == nil || !strings.Contains(err.Error(), "is down") {
+		t.Fatalf("wrote during the backoff: %v", err)
+	}
+
+	s.retryAt = time.Time{}
+	if _, err := s.Write([]byte("reconnected\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNetSinkWriteDeadline(t *testing.T) {
+	timeout := netSinkWriteTimeout
+	netSinkWriteTimeout = 50 * time.Millisecond
+	defer func() { netSinkWriteTimeout = timeout }()
+
+	// a collector that accepts but never reads
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			<-stop
+			conn.Close()
+		}
+	}()
+
+	ws, err := newNetSink(OutputConfig{Type: SinkTCP, Address: ln.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.(*netSink).Close()
+	chunk := make([]byte, 1<<20)
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < 1000; i++ {
+			if _, err := ws.Write(chunk); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("1GB written to a collector that does not read")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a write blocked on a collector that does not read")
+	}
+}
@@ -0,0 +1,115 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const syslogFacilityUser = 1
+
+// syslogSink writes entries to the local syslog daemon over a unix socket,
+// framed as RFC 3164 messages.
+type syslogSink struct {
+	mu      sync.Mutex
+	network string
+	path    string
+	tag     string
+	conn    net.Conn
+}
+
+func newSyslogSink(output OutputConfig) (zapcore.WriteSyncer, error) {
+	tag := output.Tag
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+	paths := []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+	if output.Path != "" {
+		paths = []string{output.Path}
+	}
+	for _, path := range paths {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := net.Dial(network, path)
+			if err == nil {
+				return &syslogSink{network: network, path: path, tag: tag, conn: conn}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no syslog socket found in %v", paths)
+}
+
+func (s *syslogSink) Write(p []byte) (int, error) {
+	return s.WriteLevel(zapcore.InfoLevel, p)
+}
+
+func (s *syslogSink) WriteLevel(lvl zapcore.Level, p []byte) (int, error) {
+	msg := bytes.TrimRight(p, "\n")
+	var b bytes.Buffer
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(syslogFacilityUser*8 + syslogSeverity(lvl)))
+	b.WriteByte('>')
+	b.WriteString(time.Now().Format(time.Stamp))
+	b.WriteByte(' ')
+	b.WriteString(s.tag)
+	b.WriteByte('[')
+	b.WriteString(strconv.Itoa(os.Getpid()))
+	b.WriteString("]: ")
+	b.Write(msg)
+	if s.network == "unix" {
+		b.WriteByte('\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		conn, err := net.Dial(s.network, s.path)
+		if err != nil {
+			return 0, err
+		}
+		s.conn = conn
+	}
+	if _, err := s.conn.Write(b.Bytes()); err != nil {
+		// the daemon may have been restarted, reconnect on the next write
+		s.conn.Close()
+		s.conn = nil
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *syslogSink) Sync() error {
+	return nil
+}
+
+func (s *syslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func syslogSeverity(lvl zapcore.Level) int {
+	switch lvl {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	default:
+		return 2
+	}
+}
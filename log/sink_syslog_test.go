@@ -0,0 +1,51 @@
+//go:build !windows
+
+package log
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestSyslogSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	l, err := New(&Config{Level: "debug", Outputs: []OutputConfig{{Type: SinkSyslog, Path: path, Tag: "myapp"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.Debug("debug entry")
+	l.Info("info entry")
+	l.Warn("warn entry")
+	l.Error("error entry")
+
+	// facility user (1) * 8 + the severity of the level
+	for _, want := range []struct {
+		pri int
+		msg string
+	}{{15, "debug entry"}, {14, "info entry"}, {12, "warn entry"}, {11, "error entry"}} {
+		buf := make([]byte, 4096)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := string(buf[:n])
+		re := fmt.Sprintf(`^<%d>[A-Z][a-z]{2} [ 0-9]\d \d\d:\d\d:\d\d myapp\[%d\]: .*message=%s$`,
+			want.pri, os.Getpid(), regexp.QuoteMeta(`"`+want.msg+`"`))
+		if !regexp.MustCompile(re).MatchString(msg) {
+			t.Errorf("got %q, want a match of %s", msg, re)
+		}
+	}
+}
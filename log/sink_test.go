@@ -0,0 +1,195 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// lockedBuffer is a sink that can be written and read concurrently.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Sync() error { return nil }
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *lockedBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+func (b *lockedBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+var testSinks atomic.Int64
+
+// testSink registers a sink type writing to a new buffer, removed when t
+// ends, and returns the type and the buffer.
+func testSink(t testing.TB) (string, *lockedBuffer) {
+	typ := fmt.Sprintf("test-%d", testSinks.Add(1))
+	buf := &lockedBuffer{}
+	RegisterSink(typ, func(OutputConfig) (zapcore.WriteSyncer, error) { return buf, nil })
+	t.Cleanup(func() {
+		sinkMu.Lock()
+		delete(sinkTypes, typ)
+		sinkMu.Unlock()
+	})
+	return typ, buf
+}
+
+// newBufferLogger returns a logger built from conf writing to the returned
+// buffer only, closed when t ends.
+func newBufferLogger(t testing.TB, conf *Config) (*Logger, *lockedBuffer) {
+	t.Helper()
+	typ, buf := testSink(t)
+	conf.Outputs = []OutputConfig{{Type: typ}}
+	l, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l, buf
+}
+
+func TestOutputs(t *testing.T) {
+	jsonSink, jsonBuf := testSink(t)
+	kvSink, kvBuf := testSink(t)
+	log := NewSugaredLogger(&Config{
+		Format: "kv",
+		Level:  "debug",
+		Outputs: []OutputConfig{
+			{Type: jsonSink, Format: "json", Level: "warn"},
+			{Type: kvSink},
+		},
+	})
+	log.Debugw("debug message", "k", "v")
+	log.Warnw("warn message", "k", "v")
+
+	if strings.Contains(jsonBuf.String(), "debug message") {
+		t.Errorf("json output got a debug entry: %s", jsonBuf.String())
+	}
+	if !strings.Contains(jsonBuf.String(), `"message":"warn message"`) {
+		t.Errorf("json output misses the warn entry: %s", jsonBuf.String())
+	}
+	if got := strings.Count(kvBuf.String(), "\n"); got != 2 {
+		t.Errorf("kv output got %d lines, want 2: %s", got, kvBuf.String())
+	}
+
+	// the level of the output adds to the level of the logger
+	jsonBuf.Reset()
+	lvl := zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	zl, err := initZapLogger(&Config{Level: "debug", Outputs: []OutputConfig{{Type: jsonSink, Format: "json", Level: "info"}}}, lvl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lvl.SetLevel(zapcore.ErrorLevel)
+	zl.Warn("filtered by the logger")
+	if jsonBuf.Len() != 0 {
+		t.Errorf("json output ignored the level of the logger: %s", jsonBuf.String())
+	}
+
+	if _, err := initZapLogger(&Config{Outputs: []OutputConfig{{Type: "nope"}}}, level); err == nil {
+		t.Error("expected an error for an unknown sink type")
+	}
+}
+
+func TestErrorFilePath(t *testing.T) {
+	dir := t.TempDir()
+	mainPath, errorPath := filepath.Join(dir, "app.log"), filepath.Join(dir, "error.log")
+	log, err := New(&Config{
+		Level:         "debug",
+		Outputs:       []OutputConfig{{Type: SinkFile, Path: mainPath}},
+		ErrorFilePath: errorPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Debug("debug message")
+	log.Info("info message")
+	log.Warn("warn message")
+	log.Error("error message")
+
+	main, _ := os.ReadFile(mainPath)
+	errs, _ := os.ReadFile(errorPath)
+	if got := strings.Count(string(main), "\n"); got != 4 {
+		t.Errorf("main file got %d lines, want 4: %s", got, main)
+	}
+	for _, msg := range []string{"debug message", "info message"} {
+		if strings.Contains(string(errs), msg) {
+			t.Errorf("error file got %q: %s", msg, errs)
+		}
+	}
+	for _, msg := range []string{"warn message", "error message"} {
+		if !strings.Contains(string(errs), msg) {
+			t.Errorf("error file misses %q: %s", msg, errs)
+		}
+	}
+
+	if _, err := New(&Config{FilePath: mainPath, ErrorFilePath: mainPath}); err == nil {
+		t.Error("expected an error for the same main and error file")
+	}
+}
+
+func TestOutputMode(t *testing.T) {
+	tests := []struct {
+		conf Config
+		want []string
+	}{
+		{Config{}, []string{SinkStdout}},
+		{Config{FilePath: "app.log"}, []string{SinkStdout, SinkFile}},
+		{Config{FilePath: "app.log", OutputMode: OutputFile}, []string{SinkFile}},
+		{Config{FilePath: "app.log", OutputMode: OutputConsole, Console: SinkStderr}, []string{SinkStderr}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, output := range defaultOutputs(&tt.conf) {
+			got = append(got, output.Type)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%+v: got outputs %v, want %v", tt.conf, got, tt.want)
+		}
+	}
+
+	for _, conf := range []Config{{OutputMode: OutputFile}, {OutputMode: "syslog"}, {Console: SinkFile}} {
+		if err := conf.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", conf)
+		}
+	}
+
+	// files never get colored levels
+	path := filepath.Join(t.TempDir(), "app.log")
+	log, err := New(&Config{Format: FormatCommon, FilePath: path, OutputMode: OutputFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Warn("plain level")
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), " WARN ") || strings.Contains(string(data), "\x1b[") {
+		t.Errorf("file got %q, want a plain WARN level", data)
+	}
+}
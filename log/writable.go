@@ -0,0 +1,13 @@
+//go:build !windows
+
+package log
+
+import "syscall"
+
+// wOK is the W_OK mode of access(2).
+const wOK = 2
+
+// canWrite reports whether the process may write to path, without writing.
+func canWrite(path string) error {
+	return syscall.Access(path, wOK)
+}
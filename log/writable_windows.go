@@ -0,0 +1,21 @@
+//go:build windows
+
+package log
+
+import (
+	"errors"
+	"os"
+)
+
+// canWrite reports whether the read-only attribute of path is clear;
+// windows has no access(2), and directories ignore the attribute.
+func canWrite(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() && info.Mode().Perm()&0200 == 0 {
+		return errors.New("read-only file")
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package log
 
 import (
+	"errors"
+	"io"
 	"os"
 	"strings"
 
@@ -20,104 +22,233 @@ const (
 	RotateMaxBackups = 15
 )
 
+// NewLogger builds a zap logger from conf. It panics if conf is invalid, see
+// Config.Validate; use New to get the error instead.
 func NewLogger(conf *Config) *zap.Logger {
-	return initZapLogger(conf)
+	log, err := initZapLogger(conf, zap.NewAtomicLevelAt(toZapLevel(conf.Level)))
+	if err != nil {
+		panic(err)
+	}
+	return log
 }
 
+// NewSugaredLogger is NewLogger returning a sugared logger. It panics if
+// conf is invalid as well.
 func NewSugaredLogger(conf *Config) *zap.SugaredLogger {
-	log := initZapLogger(conf)
+	log := NewLogger(conf)
 	return log.Sugar()
 }
 
-func initZapLogger(conf *Config) *zap.Logger {
-	//log rotate
-	var rotateHook *lumberjack.Logger
-	if conf.FilePath != "" {
-		maxSize := RotateMaxSize
-		if conf.Rotate.MaxSize > 100 {
-			maxSize = conf.Rotate.MaxSize
-		}
+// initZapLogger builds a zap logger from conf. logLevel is shared by every
+// core of the logger, so changing it later takes effect immediately.
+func initZapLogger(conf *Config, logLevel zap.AtomicLevel) (*zap.Logger, error) {
+	core, closer, err := buildCore(conf, logLevel)
+	if err != nil {
+		return nil, err
+	}
+	// the zap logger is used directly, without a frame of this package to
+	// skip
+	return newZapLogger(core, closer, conf.Caller.lookup()).WithOptions(zap.AddCallerSkip(-1)), nil
+}
+
+// newZapLogger returns a logger writing to core, for the wrappers of this
+// package: the caller skips their frame. The caller is looked up if caller
+// is set. On Fatal the logger closes closer, flushing its sinks, before
+// exiting.
+func newZapLogger(core zapcore.Core, closer io.Closer, caller bool) *zap.Logger {
+	return zap.New(core, zap.WithCaller(caller), zap.AddCallerSkip(1), zap.WithFatalHook(fatalHook{closer}))
+}
+
+// buildCore builds the core of a logger from conf, see initZapLogger. The
+// returned closer flushes and closes the sinks of the core.
+func buildCore(conf *Config, logLevel zap.AtomicLevel) (core zapcore.Core, closer io.Closer, err error) {
+	if err := conf.Validate(); err != nil {
+		return nil, nil, err
+	}
 
-		maxAge := RotateMaxAge
-		if conf.Rotate.MaxAge >= 3 {
-			maxAge = conf.Rotate.MaxAge
+	// with per logger name levels the cores log everything and
+	// nameLevelCore decides which entries get through
+	var coreLevel zapcore.LevelEnabler = logLevel
+	if len(conf.Levels) > 0 {
+		coreLevel = allLevels
+	}
+
+	redact, err := newRedactor(conf.Redact)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outputs := conf.Outputs
+	if len(outputs) == 0 {
+		outputs = defaultOutputs(conf)
+	}
+	if conf.ErrorFilePath != "" {
+		outputs = append(outputs[:len(outputs):len(outputs)], errorOutput(conf))
+	}
+
+	var sinks sinkClosers
+	defer func() {
+		if err != nil {
+			sinks.Close()
+		}
+	}()
+	cores := make([]zapcore.Core, 0, len(outputs))
+	for _, output := range outputs {
+		format := output.Format
+		if format == "" {
+			format = conf.Format
+		}
+		if format == "" {
+			format = FormatKV
 		}
 
-		maxBackups := RotateMaxBackups
-		if conf.Rotate.MaxBackups >= 3 {
-			maxBackups = conf.Rotate.MaxBackups
+		var level zapcore.LevelEnabler = coreLevel
+		if output.Level != "" {
+			level = outputLevel{LevelEnabler: coreLevel, min: toZapLevel(output.Level)}
 		}
 
-		rotateHook = &lumberjack.Logger{
-			Filename:   conf.FilePath,
-			MaxSize:    maxSize,
-			MaxBackups: maxBackups,
-			MaxAge:     maxAge,
-			Compress:   conf.Rotate.Compress,
-			LocalTime:  true,
+		ws, err := newSink(output)
+		if err != nil {
+			return nil, nil, err
+		}
+		sinks = sinks.add(ws)
+		if conf.Async.Enabled {
+			ws = newAsyncWriter(ws, conf.Async)
+			// closed before the sink it writes to
+			sinks = sinks.add(ws)
 		}
+		encoder, err := newEncoder(format, conf, isTerminal(output.Type))
+		if err != nil {
+			return nil, nil, err
+		}
+		core := newRedactCore(newSinkCore(encoder, ws, level), redact)
+		cores = append(cores, newErrorCore(core, format))
+	}
+	if conf.Ring != nil {
+		cores = append(cores, newErrorCore(newRedactCore(newRingCore(conf.Ring, coreLevel), redact), FormatJSON))
 	}
 
-	//log encoder
-	var encoder zapcore.Encoder
-	encoderConfig := zap.NewProductionEncoderConfig()
-	encoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05,000")
-	// encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-
-	encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
-	encoderConfig.EncodeDuration = zapcore.SecondsDurationEncoder
-	encoderConfig.EncodeName = zapcore.FullNameEncoder
-	encoderConfig.ConsoleSeparator = " "
-	encoderConfig.LineEnding = zapcore.DefaultLineEnding
-	encoderConfig.MessageKey = "message"
-	encoderConfig.StacktraceKey = "stacktrace"
-	encoderConfig.CallerKey = "caller"
-	encoderConfig.FunctionKey = "function"
-
-	switch conf.Format {
-	case "json":
-		encoderConfig.EncodeLevel = zapcore.LowercaseLevelEncoder
-		encoder = zapcore.NewJSONEncoder(encoderConfig)
-	case "console":
-		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-		encoder = zapcore.NewConsoleEncoder(encoderConfig)
-	case "common":
-		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-		encoder = NewCommonEncoder(encoderConfig)
-	default:
-		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-		encoder = NewkvEncoder(encoderConfig)
-	}
-
-	//log Level
-	logLevel := zap.NewAtomicLevel()
-	logLevel.SetLevel(toZapLevel(conf.Level))
-
-	//log fileWrites consoleWrites
-	var fileWrites zapcore.WriteSyncer
-	var consoleWrites zapcore.WriteSyncer
-	var core zapcore.Core
-
-	consoleWrites = zapcore.AddSync(os.Stdout)
-	if rotateHook != nil {
-		fileWrites = zapcore.AddSync(rotateHook)
-		fileCore := zapcore.NewCore(encoder, fileWrites, logLevel)
-		consoleCore := zapcore.NewCore(encoder, consoleWrites, logLevel)
-		core = zapcore.NewTee(fileCore, consoleCore)
+	if len(cores) == 1 {
+		core = cores[0]
 	} else {
-		core = zapcore.NewCore(encoder, consoleWrites, logLevel)
+		core = zapcore.NewTee(cores...)
+	}
+	core = newSamplerCore(core, conf.Sampling)
+	core = newRateLimitCore(core, conf.RateLimit)
+	core = newMetricsCore(core, conf.Metrics)
+	core = newNameLevelCore(core, logLevel, conf.Levels)
+	core = newCallerCore(core, conf.Caller)
+	return core, sinks, nil
+}
+
+// outputLevel is the level of an output with a Level: the entries must be
+// enabled by the level of the logger, which may change at runtime, and be
+// at min or above.
+type outputLevel struct {
+	zapcore.LevelEnabler
+	min zapcore.Level
+}
+
+func (l outputLevel) Enabled(lvl zapcore.Level) bool {
+	return lvl >= l.min && l.LevelEnabler.Enabled(lvl)
+}
+
+// sinkClosers closes the sinks of a logger, in the reverse order they were
+// added. The standard output and error are left open, for the next outputs
+// and the rest of the process.
+type sinkClosers []io.Closer
+
+func (s sinkClosers) add(ws zapcore.WriteSyncer) sinkClosers {
+	if ws == os.Stdout || ws == os.Stderr {
+		return s
+	}
+	if c, ok := ws.(io.Closer); ok {
+		return append(s, c)
+	}
+	return s
+}
+
+func (s sinkClosers) Close() error {
+	var errs []error
+	for i := len(s) - 1; i >= 0; i-- {
+		if err := s[i].Close(); err != nil {
+			errs = append(errs, err)
+		}
 	}
+	return errors.Join(errs...)
+}
 
-	zapLogger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
-	return zapLogger
+// defaultOutputs returns the outputs used when Config.Outputs is empty: the
+// console, plus a rotating file if FilePath is set, as OutputMode allows.
+func defaultOutputs(conf *Config) []OutputConfig {
+	var outputs []OutputConfig
+	if conf.OutputMode != OutputFile || conf.FilePath == "" {
+		console := conf.Console
+		if console == "" {
+			console = SinkStdout
+		}
+		outputs = append(outputs, OutputConfig{Type: console})
+	}
+	if conf.FilePath != "" && conf.OutputMode != OutputConsole {
+		outputs = append(outputs, OutputConfig{
+			Type:   SinkFile,
+			Path:   conf.FilePath,
+			Rotate: conf.Rotate,
+		})
+	}
+	return outputs
 }
 
+// errorOutput returns the output of Config.ErrorFilePath, a file filtered to
+// warn and above.
+func errorOutput(conf *Config) OutputConfig {
+	return OutputConfig{
+		Type:   SinkFile,
+		Level:  zapcore.WarnLevel.String(),
+		Path:   conf.ErrorFilePath,
+		Rotate: conf.ErrorRotate,
+	}
+}
+
+// newRotateHook returns the lumberjack logger of a file output. Zero
+// rotation settings take the Rotate* defaults.
+func newRotateHook(path string, rotate RotateConfig) *lumberjack.Logger {
+	maxSize := RotateMaxSize
+	if rotate.MaxSize > 0 {
+		maxSize = rotate.MaxSize
+	}
+
+	maxAge := RotateMaxAge
+	if rotate.MaxAge > 0 {
+		maxAge = rotate.MaxAge
+	}
+
+	maxBackups := RotateMaxBackups
+	if rotate.MaxBackups > 0 {
+		maxBackups = rotate.MaxBackups
+	}
+
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSize,
+		MaxBackups: maxBackups,
+		MaxAge:     maxAge,
+		Compress:   rotate.Compress,
+		LocalTime:  true,
+	}
+}
+
+// toZapLevel parses a level of a validated Config.
 func toZapLevel(level string) zapcore.Level {
-	level = strings.ToLower(level)
-	logLevel := zap.NewAtomicLevel()
-	err := logLevel.UnmarshalText([]byte(level))
+	lvl, err := parseLevel(level)
 	if err != nil {
-		logLevel.SetLevel(zapcore.InfoLevel)
+		return zapcore.InfoLevel
 	}
-	return logLevel.Level()
+	return lvl
+}
+
+func parseLevel(level string) (zapcore.Level, error) {
+	var lvl zapcore.Level
+	err := lvl.UnmarshalText([]byte(strings.ToLower(level)))
+	return lvl, err
 }
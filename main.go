@@ -15,11 +15,13 @@ func main() {
 		Format: "common",
 	}
 	log.Init(logConfig)
+	defer log.WatchLevelSignals()()
 	middleware.InitLoggerMiddleware(logConfig)
 	r.Use(middleware.Logger())
 	// r.Use(gin.Recovery())
 	r.Use(middleware.Recovery())
 
+	r.Any("/log/level", middleware.LogLevel())
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "pong",
@@ -3,6 +3,7 @@ package prometheus
 import (
 	"math/rand"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -19,8 +20,8 @@ type Collector interface {
 
 // metadata basic metadata of prometheus.Collector
 type collectorMetadata struct {
-	idx              string    // idx is used to identify the collector
-	lastAccessedTime time.Time // updateAt is the time when the collector is updated
+	idx              string       // idx is used to identify the collector
+	lastAccessedTime atomic.Int64 // lastAccessedTime is the unix nano time the collector was last updated, touched concurrently
 }
 
 func (c *collectorMetadata) Idx() string {
@@ -28,17 +29,18 @@ func (c *collectorMetadata) Idx() string {
 }
 
 func (c *collectorMetadata) touch() {
-	c.lastAccessedTime = time.Now()
+	c.lastAccessedTime.Store(time.Now().UnixNano())
 }
 
 func (c *collectorMetadata) Expired(duration time.Duration) bool {
-	return time.Since(c.lastAccessedTime) > duration
+	return time.Since(time.Unix(0, c.lastAccessedTime.Load())) > duration
 }
 
 func NewCollectorMetadata() *collectorMetadata {
 	var randint int64 = int64(rand.New(rand.NewSource(time.Now().UnixNano())).Intn(100)) * 10
-	return &collectorMetadata{
-		idx:              strconv.FormatInt(time.Now().UnixNano()+randint, 36),
-		lastAccessedTime: time.Now(),
+	c := &collectorMetadata{
+		idx: strconv.FormatInt(time.Now().UnixNano()+randint, 36),
 	}
+	c.touch()
+	return c
 }
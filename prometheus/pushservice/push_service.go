@@ -4,19 +4,28 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lunuan/gopkg/log"
 	"github.com/lunuan/gopkg/prometheus"
-	"go.uber.org/zap"
 
 	"github.com/prometheus/client_golang/prometheus/push"
 )
 
-var logger *zap.SugaredLogger
+// logger is the logger of the push service, the global logger named
+// pushservice unless SetLogger changed it. It follows the global config, so
+// Config.Levels can set its level with the "pushservice" key.
+var logger atomic.Pointer[log.Logger]
 
 func init() {
-	logger = log.NewSugaredLogger(&log.Config{Level: "debug"}).Named("pushservice")
+	logger.Store(log.L().Named("pushservice"))
+}
+
+// SetLogger makes the push service log with l instead of the global logger.
+// It applies to the services and tasks running already as well.
+func SetLogger(l *log.Logger) {
+	logger.Store(l)
 }
 
 type PushTask struct {
@@ -29,7 +38,7 @@ type PushTask struct {
 }
 
 func newPushTask(url string, interval time.Duration, collector prometheus.Collector) *PushTask {
-	logger.Debugw("new push task", "url", url, "interval", interval, "collector", collector)
+	logger.Load().Debugw("new push task", "url", url, "interval", interval, "collector", collector)
 	return &PushTask{
 		url:       url,
 		interval:  interval,
@@ -42,14 +51,14 @@ func newPushTask(url string, interval time.Duration, collector prometheus.Collec
 func (t *PushTask) run() {
 	defer func() {
 		if r := recover(); r != nil {
-			logger.Errorw("recovered", "collector", t.collector.Idx(), "panic", r)
+			logger.Load().Errorw("recovered", "collector", t.collector.Idx(), "panic", r)
 		}
 		t.running = false
 		t.mux.Unlock()
 	}()
 	t.mux.Lock()
 	t.running = true
-	logger.Infow("task started", "collector", t.collector.Idx())
+	logger.Load().Infow("task started", "collector", t.collector.Idx())
 	ctx, cancel := context.WithCancel(context.Background())
 	t.cancel = &cancel
 	pusher := push.New(t.url, fmt.Sprintf("pushservice_%s", t.collector.Idx()))
@@ -61,11 +70,11 @@ func (t *PushTask) run() {
 		default:
 			err := pusher.Push()
 			if err != nil {
-				logger.Error(err.Error())
+				logger.Load().Error(err.Error())
 			}
 			taskPushTotal.WithLabelValues(t.collector.Idx()).Inc()
 		}
-		logger.Debugw("push request success", "collector", t.collector.Idx())
+		logger.Load().Debugw("push request success", "collector", t.collector.Idx())
 		time.Sleep(t.interval)
 	}
 }
@@ -92,7 +101,7 @@ func (t *PushTask) stop(cause int) {
 	}
 	t.running = false
 
-	logger.Infow("task stoped", "collector", t.collector.Idx(), "cause", taskStopedCause[cause])
+	logger.Load().Infow("task stoped", "collector", t.collector.Idx(), "cause", taskStopedCause[cause])
 
 	// delete metrics from pushgateway if the task is stopped by expired collector or unregister collector
 	if cause == 1 || cause == 2 {
@@ -100,10 +109,10 @@ func (t *PushTask) stop(cause int) {
 		pusher.Collector(t.collector)
 		err := pusher.Delete()
 		if err != nil {
-			logger.Errorw("failed to delete metrics in pushgateway", "collector", t.collector.Idx(), "error", err)
+			logger.Load().Errorw("failed to delete metrics in pushgateway", "collector", t.collector.Idx(), "error", err)
 			return
 		}
-		logger.Infow("deleted from pushgateway", "collector", t.collector.Idx())
+		logger.Load().Infow("deleted from pushgateway", "collector", t.collector.Idx())
 	}
 }
 
@@ -135,14 +144,14 @@ func (s *PushService) start() {
 	s.status = "running"
 	defer func() {
 		if r := recover(); r != nil {
-			logger.Errorw("recovered from panic", "panic", r)
+			logger.Load().Errorw("recovered from panic", "panic", r)
 		}
 		s.status = "stopped"
 	}()
-	logger.Infow("push service started", "url", s.url, "interval", s.interval, "expired", s.expired)
+	logger.Load().Infow("push service started", "url", s.url, "interval", s.interval, "expired", s.expired)
 	for {
 		// check push service status for each collector every interval duration
-		logger.Debugw("check push task status for each collector", "expired", s.expired)
+		logger.Load().Debugw("check push task status for each collector", "expired", s.expired)
 		s.mutex.Lock()
 		for _, c := range s.collectors {
 			task := s.tasks[c.Idx()]
@@ -150,14 +159,14 @@ func (s *PushService) start() {
 			if c.Expired(s.expired) && task.running {
 				task.stop(1)
 			} else if !c.Expired(s.expired) && !task.running {
-				logger.Infof("task %s is unexpired but not running, restart", c.Idx())
+				logger.Load().Infof("task %s is unexpired but not running, restart", c.Idx())
 				go task.run()
 			}
 		}
 
 		for id, task := range s.tasks {
 			if _, ok := s.collectors[id]; !ok {
-				logger.Warnw("orphan task found", "task", id)
+				logger.Load().Warnw("orphan task found", "task", id)
 				task.stop(4)
 				delete(s.tasks, id)
 			}
@@ -177,18 +186,18 @@ func (s *PushService) Register(c prometheus.Collector) {
 	task := newPushTask(s.url, s.interval, c)
 	s.tasks[c.Idx()] = task
 	go task.run()
-	logger.Infow("collector registered", "idx", c.Idx())
+	logger.Load().Infow("collector registered", "idx", c.Idx())
 }
 
 // Unregister unregisters the collector from the push service
 func (s *PushService) Unregister(c prometheus.Collector) {
-	logger.Infow("unregister collector", "collector", c.Idx())
+	logger.Load().Infow("unregister collector", "collector", c.Idx())
 	defer func() {
 		if r := recover(); r != nil {
-			logger.Errorw("recovered from panic", "panic", r)
+			logger.Load().Errorw("recovered from panic", "panic", r)
 			return
 		}
-		logger.Infof("unregister collector %d complete", c.Idx())
+		logger.Load().Infof("unregister collector %d complete", c.Idx())
 	}()
 
 	s.mutex.Lock()
@@ -215,5 +224,5 @@ func (s *PushService) Stop() {
 		wg.Done()
 	}
 	wg.Wait()
-	logger.Infof("push service stopped")
+	logger.Load().Infof("push service stopped")
 }
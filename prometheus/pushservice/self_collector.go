@@ -31,7 +31,7 @@ func (c *PushServiceCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (c *PushServiceCollector) Collect(ch chan<- prometheus.Metric) {
-	logger.Debug("collect metrics")
+	logger.Load().Debug("collect metrics")
 
 	// registered collector count in push service
 	ch <- prometheus.MustNewConstMetric(